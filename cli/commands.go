@@ -1,10 +1,12 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -23,6 +25,8 @@ func previewCmd(env environ.Values) *cobra.Command {
 	var cfgFile string
 	var verbose bool
 	var format string
+	var timeout time.Duration
+	var strict bool
 	preview := &cobra.Command{
 		Use:   "preview",
 		Short: "Preview the data that will be sent to your templates",
@@ -32,8 +36,10 @@ just as it would be during a full run.  It is then printed out in an
 easy-to-read format.  By default it prints out the data in a human-readable
 plaintext tabular format.  You may specify a different format using the -format
 flag, in which case you can print json, yaml, or types, where types is a list of
-all types used by columns in your database.  The latter is useful when setting
-up TypeMaps.
+all types used by columns in your database, followed by the columns whose types
+were mapped by TypeRules and the rule that mapped each one, and the columns
+whose types were set by Overrides.  The latter is useful when setting up
+TypeMaps and TypeRules.
 `[1:],
 		RunE: func(cmd *cobra.Command, args []string) error {
 			env.InitLog(verbose)
@@ -54,7 +60,10 @@ up TypeMaps.
 			if err != nil {
 				return codeErr{err, 2}
 			}
-			if err := run.Preview(env, cfg, pformat); err != nil {
+			cfg.Strict = cfg.Strict || strict
+			ctx, cancel := timeoutContext(timeout)
+			defer cancel()
+			if err := run.Preview(ctx, env, cfg, pformat); err != nil {
 				return codeErr{err, 1}
 			}
 			return nil
@@ -64,12 +73,16 @@ up TypeMaps.
 	preview.Flags().StringVarP(&cfgFile, "config", "c", "gnorm.toml", "relative path to gnorm config file")
 	preview.Flags().StringVarP(&format, "format", "f", "tabular", "Specify output format: tabular, yaml, json, or types")
 	preview.Flags().BoolVarP(&verbose, "verbose", "v", false, "show debugging output")
+	preview.Flags().DurationVar(&timeout, "timeout", 0, "give up reading the database schema after this long, e.g. 2m (default no limit)")
+	preview.Flags().BoolVar(&strict, "strict", false, "fail on unmapped types and unresolved references, as if Strict were set in the config")
 	return preview
 }
 
 func genCmd(env environ.Values) *cobra.Command {
 	var cfgFile string
 	var verbose bool
+	var timeout time.Duration
+	var strict bool
 	gen := &cobra.Command{
 		Use:   "gen",
 		Short: "Generate code from DB schema",
@@ -83,7 +96,10 @@ based on those templates.`[1:],
 			if err != nil {
 				return codeErr{err, 2}
 			}
-			if err := run.Generate(env, cfg); err != nil {
+			cfg.Strict = cfg.Strict || strict
+			ctx, cancel := timeoutContext(timeout)
+			defer cancel()
+			if err := run.Generate(ctx, env, cfg); err != nil {
 				return codeErr{err, 1}
 			}
 			return nil
@@ -92,9 +108,20 @@ based on those templates.`[1:],
 	}
 	gen.Flags().StringVarP(&cfgFile, "config", "c", "gnorm.toml", "relative path to gnorm config file")
 	gen.Flags().BoolVarP(&verbose, "verbose", "v", false, "show debugging output")
+	gen.Flags().DurationVar(&timeout, "timeout", 0, "give up reading the database schema after this long, e.g. 2m (default no limit)")
+	gen.Flags().BoolVar(&strict, "strict", false, "fail on unmapped types and unresolved references, as if Strict were set in the config")
 	return gen
 }
 
+// timeoutContext returns a context that is cancelled after the timeout, or
+// never if the timeout is zero.
+func timeoutContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
 func versionCmd(env environ.Values) *cobra.Command {
 	return &cobra.Command{
 		Use:   "version",
@@ -1,30 +1,70 @@
 package cli
 
+import "github.com/pkg/errors"
+
 // Config holds the schema that is expected to exist in the gnorm.toml file.
 type Config struct {
 	// ConnStr is the connection string for the database.  Environment variables
 	// in $FOO form will be expanded.
 	ConnStr string
 
-	// The type of DB you're connecting to.  Currently the possible values are
-	// "postgres" or "mysql".
+	// The type of DB you're connecting to.  The built-in types are "postgres",
+	// "mysql" and "generic".  For any other type, gnorm runs the external
+	// driver called gnorm-driver-<DBType> found in PluginDirs.
 	DBType string
 
 	// Schemas holds the names of schemas to generate code for.
 	Schemas []string
 
+	// ConnectTimeout limits how long opening each connection to the database
+	// may take, as a duration such as "10s".  If not set, there is no limit.
+	ConnectTimeout string
+
+	// StatementTimeout limits how long the database may spend running each
+	// query that reads the schema, as a duration such as "30s".  If not set,
+	// there is no limit.
+	StatementTimeout string
+
+	// Parallelism is the number of database connections the schema is read on.
+	// The catalog queries don't depend on each other, so they run concurrently,
+	// one per connection at a time.  Mysql can't share a snapshot between
+	// connections, so with Snapshot it reads the schema on a single
+	// connection.  Set it to 1 to run them one after the other.  The default
+	// is 4.
+	Parallelism int
+
+	// Snapshot, if true, reads the whole schema in read-only transactions with
+	// repeatable read isolation, so that all the queries see the same state
+	// of the catalog even if it changes while gnorm reads it.  Mysql can't
+	// share a snapshot between connections, so with Snapshot it reads the
+	// schema on a single connection, ignoring Parallelism.  The default is
+	// true, except on mysql, where it's false.
+	Snapshot *bool
+
+	// Strict, if true, makes gnorm fail before generating anything if there
+	// are any types not mapped by the TypeRules, TypeMap or NullableTypeMap,
+	// foreign keys or other references to tables and columns that weren't
+	// read, skipped indexes, overrides that match nothing, names that
+	// convert to an empty string, or names that collide or are reserved words
+	// and aren't suffixed by NameCollisions.  The error lists every problem
+	// with the schema.table.column it was found at.  Otherwise these are only
+	// logged with -verbose.  The --strict flag turns it on too.
+	Strict bool
+
 	// IncludeTables is a whitelist of tables to generate data for. Tables not
 	// in this list will not be included in data geenrated by gnorm. You cannot
 	// set IncludeTables if ExcludeTables is set.  By default, tables will be
 	// included in all schemas.  To specify tables for a specific schema only,
-	// use the schema.tablenmae format.
+	// use the schema.tablenmae format.  Names that contain periods may be
+	// quoted with double quotes or backticks, e.g. "my.schema"."my.table".
 	IncludeTables []string
 
 	// ExcludeTables is a blacklist of tables to ignore while generating data.
 	// All tables in a schema that are not in this list will be used for
 	// generation. You cannot set ExcludeTables if IncludeTables is set.  By
 	// default, tables will be excluded from all schemas.  To specify tables for
-	// a specific schema only, use the schema.tablenmae format.
+	// a specific schema only, use the schema.tablenmae format.  Names that
+	// contain periods may be quoted as for IncludeTables.
 	ExcludeTables []string
 
 	// TemplateEngine, if specified, describes a command line tool to run to
@@ -45,6 +85,19 @@ type Config struct {
 		// If true, the output of the tool will be written to the target file.
 		UseStdout bool
 	}
+	// Generic configures the generic driver, used when DBType is "generic",
+	// which reads the schema from the standard information_schema views using
+	// any database/sql driver compiled into gnorm.
+	Generic struct {
+		// SQLDriver is the name of the database/sql driver to connect with,
+		// either "postgres" (lib/pq) or "mysql" (go-sql-driver/mysql).
+		SQLDriver string
+
+		// Placeholder is the style of query parameter placeholders the
+		// database uses, either "?" (the default) or "$1".
+		Placeholder string
+	}
+
 	// PostRun is a command with arguments that is run after each file is
 	// generated by GNORM.  It is generally used to reformat the file, but it
 	// can be for any use. Environment variables will be expanded, and the
@@ -60,6 +113,81 @@ type Config struct {
 	// "{{pascal .}}".
 	NameConversion string
 
+	// SchemaNameConversion, TableNameConversion, ColumnNameConversion,
+	// EnumNameConversion, EnumValueNameConversion, IndexNameConversion,
+	// ForeignKeyNameConversion and RelationNameConversion, if set, override
+	// NameConversion for that kind of item.  Rather than the DB name, the "."
+	// value of these templates has the fields .DBName, the DB name of the
+	// item, .Schema, the schema it's in, and depending on the kind of item,
+	// .Table, the table being named or that the item belongs to, .Column, the
+	// column being named, .Enum, the enum being named or that the value belongs
+	// to, .ForeignKey, the foreign key being named, and .Relation, the relation
+	// being named.  Everything that belongs to an item is named after the item,
+	// so for example an enum value's template can use .Enum.Name.  Items of
+	// other kinds (domains, composite types, sequences and triggers) always use
+	// NameConversion.
+	SchemaNameConversion     string
+	TableNameConversion      string
+	ColumnNameConversion     string
+	EnumNameConversion       string
+	EnumValueNameConversion  string
+	IndexNameConversion      string
+	ForeignKeyNameConversion string
+	RelationNameConversion   string
+
+	// NameCollisions is how converted names are handled that are the same as
+	// another name in the same scope (the schemas, the tables and enums of a
+	// schema, the columns of a table, or the values of an enum), or are
+	// reserved words.  It may be "warn" (the default), which prints a warning
+	// to stderr, even without -verbose, and leaves the names alone, "error",
+	// which fails with an error naming the DB objects involved, or "suffix",
+	// which appends a number to names that collide with a name earlier in the
+	// scope and an underscore to reserved words.
+	NameCollisions string
+
+	// ReservedLanguages is a list of languages whose reserved words converted
+	// names may not be.  The known languages are "go", "python" and
+	// "typescript".
+	ReservedLanguages []string
+
+	// ReservedWords is a list of other words converted names may not be.
+	ReservedWords []string
+
+	// MySQLEnumNaming defines the DBName of mysql enums and sets, which belong
+	// to a single column rather than being named types.  This is a template
+	// that may use all the regular functions, and may reference the values
+	// .Schema, .Table and .Column, containing the original names of the
+	// column's schema, table and column.  The default is "{{.Column}}".
+	MySQLEnumNaming string
+
+	// MergeMySQLEnums, if true, merges mysql enums (or sets) in the same schema
+	// that have identical lists of values into a single enum, which lists all
+	// the columns that use it.  The merged enum is named after the first
+	// column that uses it.
+	MergeMySQLEnums bool
+
+	// ArrayType defines the Type of array columns whose type isn't in the
+	// TypeMap or NullableTypeMap with its array shape (e.g. "int4[]" or
+	// "int4[][]").  This is a template that may use all the regular functions,
+	// and may reference the values .Type, the element type mapped with the
+	// TypeMap, .DBType, the original element type, .Dimensions, the number of
+	// dimensions of the array, .Nullable, and .TypeMap, the name of the named
+	// type map the type is from, which is empty for the TypeMap.  For example,
+	// `{{repeat "[]" .Dimensions}}{{.Type}}` maps int4[][] to [][]int32 if
+	// int4 is mapped to int32.  If not set, arrays are mapped by their element
+	// type.
+	ArrayType string
+
+	// AnnotationPattern is a regular expression that finds the annotations in
+	// the comments of tables, columns, enums, indexes and foreign keys, which
+	// are put in their .Annotations, with the rest of the comment in their
+	// .Description.  Its first subexpression is the key of an annotation, and
+	// its first other subexpression that matches is the value.  An annotation
+	// without a value has the value "true".  The default finds annotations
+	// like @deprecated, @validate:email or @json:"-" that are at the start of
+	// the comment or after whitespace.
+	AnnotationPattern string
+
 	// TablePaths is a set of "output-path" = "template-path" pairs that tells
 	// Gnorm how to render and output its table info.  Each template will be
 	// rendered with each table in turn and written out to the given output
@@ -101,9 +229,12 @@ type Config struct {
 	// (generally types from your language for deserialization).  Types not in
 	// this list will remain in their database form.  In the data sent to your
 	// template, this is the Column.Type, and the original type is in
-	// Column.OrigType.  Note that because of the way tables in TOML work,
-	// TypeMap and NullableTypeMap must be at the end of your configuration
-	// file.
+	// Column.OrigType.  Keys may also be a full column type such as
+	// "numeric(12,2)", a schema-qualified user-defined type such as
+	// "billing.currency", or for mysql a type with modifiers such as "int
+	// unsigned"; the most specific key that exists is used.  Note that because
+	// of the way tables in TOML work, TypeMap and NullableTypeMap must be at
+	// the end of your configuration file.
 	TypeMap map[string]string
 
 	// NullableTypeMap is a mapping of database type names to replacement type
@@ -116,12 +247,66 @@ type Config struct {
 	// file.
 	NullableTypeMap map[string]string
 
+	// TypeMaps are named type maps, for generating code in more than one
+	// language from the same schema.  Each maps database type names to the
+	// types of one language, looked up the same way TypeMap is, e.g.
+	// [TypeMaps.ts] maps types for typescript.  In the data sent to your
+	// template, the type from each map is in Column.Types under the map's
+	// name, e.g. Column.Types.ts.  The named maps are used the same way as
+	// the TypeMap, after the TypeRules and Overrides and with the ArrayType,
+	// whose Types, if they have one for the map, replace their Type.  Note
+	// that because of the way tables in TOML work, TypeMaps and
+	// NullableTypeMaps must be at the end of your configuration file.
+	TypeMaps map[string]map[string]string
+
+	// NullableTypeMaps are the named type maps used for nullable columns, the
+	// same way NullableTypeMap is for TypeMap.
+	NullableTypeMaps map[string]map[string]string
+
+	// TypeImports is a mapping of the types columns are mapped to, by any of
+	// the type maps, TypeRules or Overrides, to the import path of the package
+	// that declares them, e.g. "uuid.UUID" = "github.com/google/uuid".  A key
+	// may also be the package qualifier of the types, e.g. "sql" =
+	// "database/sql" for all of sql.NullString, sql.NullInt64 and so on.  To
+	// import the package with an alias, use a table with Path and Alias, e.g.
+	// "dec.Decimal" = { Path = "github.com/shopspring/decimal", Alias = "dec" }.
+	// Types are read as Go type expressions, so a type like
+	// map[uuid.UUID]dec.Decimal or []*time.Time needs the imports of every
+	// qualified type in it; a type that isn't valid Go is looked up as a
+	// whole.  In the data sent to your template, the imports needed by the
+	// columns of a table, or of all the tables in a schema, are in
+	// Table.Imports and Schema.Imports, sorted and without duplicates.
+	TypeImports map[string]TypeImport
+
+	// TargetTypeMaps is a set of "output-path" = "type-map-name" pairs that
+	// selects the named type map whose types are the Column.Type of every
+	// column while rendering the TablePaths, SchemaPaths or EnumPaths entry
+	// with that output path.  Output paths not listed here use the TypeMap
+	// and NullableTypeMap.
+	TargetTypeMaps map[string]string
+
+	// TypeRules is a list of rules that map the types of columns by more than
+	// their database type, checked in order before the TypeMap and
+	// NullableTypeMap.  The first rule that matches a column sets its Type,
+	// and the rule's name is the column's TypeSource.  Note that because of
+	// the way tables in TOML work, TypeRules must be at the end of your
+	// configuration file.
+	TypeRules []TypeRule
+
 	// Params contains any data you may want to pass to your templates.  This is
 	// a good way to make templates reusable with different configuration values
 	// for different situations.  The values in this field will be available in
 	// the .Params value for all templates.
 	Params map[string]interface{}
 
+	// Overrides adjust single tables and columns without changing the
+	// database.  The keys are "schema.table" for a table or
+	// "schema.table.column" for a column, quoting names that contain periods
+	// as for IncludeTables.  Tables may only override Name and Params.  Note
+	// that because of the way tables in TOML work, Overrides must be at the
+	// end of your configuration file.
+	Overrides map[string]Override
+
 	// PluginDirs a list of paths that will be used for finding plugins.  The
 	// list will be traversed in order, looking for a specifically named plugin.
 	// The first plugin that is found will be the one used.
@@ -148,3 +333,118 @@ type Config struct {
 	// *and* a file exists with that name, it will not be generated.
 	NoOverwriteGlobs []string
 }
+
+// Override holds the values that replace what gnorm would otherwise generate
+// for a single table or column.
+type Override struct {
+	// Name, if set, replaces the converted name of the table or column.
+	Name string
+
+	// Type, if set, replaces the type of the column mapped with the TypeMap or
+	// NullableTypeMap.
+	Type string
+
+	// Types, by the name of one of the TypeMaps, replace Type in the types
+	// from that map, e.g. Types = { ts = "Secret" }.
+	Types map[string]string
+
+	// Exclude, if true, leaves the column out of the data, along with any
+	// index that includes it and any foreign key that includes or references
+	// it.
+	Exclude bool
+
+	// Nullable, if set, replaces whether the column is nullable.  The column's
+	// type is mapped with the NullableTypeMap if it's true, and the TypeMap if
+	// it's false.
+	Nullable *bool
+
+	// PrimaryKey, if true, makes the column part of the table's primary key.
+	// This is mostly useful for views, which have no primary key of their own.
+	PrimaryKey bool
+
+	// Params contains any data you want to attach to the table or column.  It
+	// is available in templates as the table's or column's .Params value.
+	Params map[string]interface{}
+}
+
+// TypeRule maps the type of the columns it matches.  A column matches if it
+// matches everything set in the rule.  DBType, Schema, Table and Column are
+// patterns: globs matched against the whole name, where * matches any run of
+// characters, ? any single character, and [abc] or [!abc] a character class,
+// or regular expressions if written between slashes, e.g. "/^int[248]$/".
+type TypeRule struct {
+	// Name identifies the rule in the TypeSource of the columns it maps, and
+	// in preview.  The default is TypeRules[n], where n is the rule's index.
+	Name string
+
+	// DBType matches the column's type, or any of the other keys its type may
+	// be mapped with in the TypeMap, e.g. "numeric(12,2)" as well as
+	// "numeric".  Arrays match by their element type, but only if Array is
+	// true.
+	DBType string
+
+	// Schema, Table and Column match the database names of the column's
+	// schema, table and column.
+	Schema string
+	Table  string
+	Column string
+
+	// Length, Precision and Scale, if set, match the column's exactly.
+	Length    *int
+	Precision *int
+	Scale     *int
+
+	// Array and Nullable, if set, match whether the column is an array and
+	// whether it is nullable.  Since Type is the type of the whole column,
+	// arrays only match rules with Array = true.
+	Array    *bool
+	Nullable *bool
+
+	// Type is the type the rule maps the columns it matches to.
+	Type string
+
+	// Types, by the name of one of the TypeMaps, replace Type in the types
+	// from that map, e.g. Types = { ts = "string" }.  A rule with Types but
+	// no Type only maps the columns it matches in those maps.
+	Types map[string]string
+}
+
+// TypeImport is the package a type is imported from.  In the config file it
+// may be just the import path, or a table with Path and Alias.
+type TypeImport struct {
+	// Path is the import path of the package.
+	Path string
+
+	// Alias, if set, is the name the package is imported as.
+	Alias string
+}
+
+// UnmarshalTOML implements toml.Unmarshaler, so an import may be given as a
+// string or a table.
+func (t *TypeImport) UnmarshalTOML(v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		t.Path = v
+	case map[string]interface{}:
+		for key, val := range v {
+			s, ok := val.(string)
+			if !ok {
+				return errors.Errorf("expected %s of type import to be a string, got %v", key, val)
+			}
+			switch key {
+			case "Path":
+				t.Path = s
+			case "Alias":
+				t.Alias = s
+			default:
+				return errors.Errorf("unknown type import key %q, expected Path or Alias", key)
+			}
+		}
+	default:
+		return errors.Errorf("expected type import to be an import path or a table with Path and Alias, got %v", v)
+	}
+	if t.Path == "" {
+		return errors.New("type import has no Path")
+	}
+	return nil
+}
@@ -1,24 +1,34 @@
 package cli // import "gnorm.org/gnorm/cli"
 
 import (
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"regexp"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/pkg/errors"
 
 	"gnorm.org/gnorm/database"
-	"gnorm.org/gnorm/database/drivers/mysql"
-	"gnorm.org/gnorm/database/drivers/postgres"
+	"gnorm.org/gnorm/database/drivers/external"
+	// register the built-in drivers
+	_ "gnorm.org/gnorm/database/drivers/generic"
+	_ "gnorm.org/gnorm/database/drivers/mysql"
+	_ "gnorm.org/gnorm/database/drivers/postgres"
 	"gnorm.org/gnorm/environ"
 	"gnorm.org/gnorm/run"
 	"gnorm.org/gnorm/run/data"
 )
 
+// defaultParallelism is the number of connections the schema is read on if
+// Parallelism isn't set.
+const defaultParallelism = 4
+
 func parseFile(env environ.Values, file string) (*run.Config, error) {
 	f, err := os.Open(file)
 	if err != nil {
@@ -71,6 +81,8 @@ func Parse(env environ.Values, r io.Reader) (*run.Config, error) {
 			Schemas:          c.Schemas,
 			NullableTypeMap:  c.NullableTypeMap,
 			TypeMap:          c.TypeMap,
+			TypeMaps:         c.TypeMaps,
+			NullableTypeMaps: c.NullableTypeMaps,
 			PostRun:          c.PostRun,
 			ExcludeTables:    exclude,
 			IncludeTables:    include,
@@ -78,14 +90,113 @@ func Parse(env environ.Values, r io.Reader) (*run.Config, error) {
 			StaticDir:        c.StaticDir,
 			PluginDirs:       c.PluginDirs,
 			NoOverwriteGlobs: c.NoOverwriteGlobs,
+			MergeMySQLEnums:  c.MergeMySQLEnums,
 		},
 		Params: c.Params,
+		Strict: c.Strict,
 	}
-	d, err := getDriver(strings.ToLower(c.DBType))
+	cfg.Driver, err = getDriver(strings.ToLower(c.DBType), c.PluginDirs)
 	if err != nil {
 		return nil, err
 	}
-	cfg.Driver = d
+	if d, ok := cfg.Driver.(database.Configurer); ok {
+		cfg.Driver, err = d.Configure(map[string]string{
+			"SQLDriver":   c.Generic.SQLDriver,
+			"Placeholder": c.Generic.Placeholder,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.ConnectTimeout != "" {
+		cfg.ConnectTimeout, err = time.ParseDuration(c.ConnectTimeout)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing ConnectTimeout")
+		}
+	}
+	if c.StatementTimeout != "" {
+		cfg.StatementTimeout, err = time.ParseDuration(c.StatementTimeout)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing StatementTimeout")
+		}
+	}
+
+	switch {
+	case c.Parallelism == 0:
+		cfg.Parallelism = defaultParallelism
+	case c.Parallelism < 0:
+		return nil, errors.Errorf("Parallelism must be at least 1, got %d", c.Parallelism)
+	default:
+		cfg.Parallelism = c.Parallelism
+	}
+	if c.Snapshot != nil {
+		cfg.Snapshot = *c.Snapshot
+	} else {
+		// mysql can only read a consistent snapshot on a single connection,
+		// so it defaults to reading on Parallelism connections instead.
+		cfg.Snapshot = strings.ToLower(c.DBType) != "mysql"
+	}
+
+	switch strings.ToLower(c.NameCollisions) {
+	case "", run.CollisionWarn:
+		cfg.NameCollisions = run.CollisionWarn
+	case run.CollisionError, run.CollisionSuffix:
+		cfg.NameCollisions = strings.ToLower(c.NameCollisions)
+	default:
+		return nil, errors.Errorf("unknown NameCollisions value %q, expected one of %q, %q or %q", c.NameCollisions, run.CollisionError, run.CollisionSuffix, run.CollisionWarn)
+	}
+	for _, lang := range c.ReservedLanguages {
+		words, err := run.ReservedWords(lang)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing ReservedLanguages")
+		}
+		cfg.ReservedWords = append(cfg.ReservedWords, words...)
+	}
+	cfg.ReservedWords = append(cfg.ReservedWords, c.ReservedWords...)
+
+	if len(c.TypeImports) > 0 {
+		cfg.TypeImports = make(map[string]data.Import, len(c.TypeImports))
+		for typ, imp := range c.TypeImports {
+			cfg.TypeImports[typ] = data.Import{Path: imp.Path, Alias: imp.Alias}
+		}
+	}
+
+	pattern := c.AnnotationPattern
+	if pattern == "" {
+		pattern = run.DefaultAnnotationPattern
+	}
+	cfg.AnnotationPattern, err = regexp.Compile(pattern)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error parsing AnnotationPattern")
+	}
+	if cfg.AnnotationPattern.NumSubexp() == 0 {
+		return nil, errors.Errorf("AnnotationPattern %q has no subexpression for the key of an annotation", pattern)
+	}
+
+	cfg.TypeRules, err = parseTypeRules(c.TypeRules)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.Overrides, err = parseOverrides(c.Overrides, c.Schemas)
+	if err != nil {
+		return nil, err
+	}
+	for x, r := range c.TypeRules {
+		for name := range r.Types {
+			if !hasTypeMap(c, name) {
+				return nil, errors.Errorf("type rule %s has Types for unknown type map %q", cfg.TypeRules[x].Name, name)
+			}
+		}
+	}
+	for k, o := range c.Overrides {
+		for name := range o.Types {
+			if !hasTypeMap(c, name) {
+				return nil, errors.Errorf("override %q has Types for unknown type map %q", k, name)
+			}
+		}
+	}
 
 	environ.FuncMap["plugin"] = environ.Plugin(c.PluginDirs)
 
@@ -95,6 +206,46 @@ func Parse(env environ.Values, r io.Reader) (*run.Config, error) {
 	}
 	cfg.NameConversion = t
 
+	for _, n := range []struct {
+		name string
+		text string
+		dest **template.Template
+	}{
+		{"SchemaNameConversion", c.SchemaNameConversion, &cfg.SchemaNameConversion},
+		{"TableNameConversion", c.TableNameConversion, &cfg.TableNameConversion},
+		{"ColumnNameConversion", c.ColumnNameConversion, &cfg.ColumnNameConversion},
+		{"EnumNameConversion", c.EnumNameConversion, &cfg.EnumNameConversion},
+		{"EnumValueNameConversion", c.EnumValueNameConversion, &cfg.EnumValueNameConversion},
+		{"IndexNameConversion", c.IndexNameConversion, &cfg.IndexNameConversion},
+		{"ForeignKeyNameConversion", c.ForeignKeyNameConversion, &cfg.ForeignKeyNameConversion},
+		{"RelationNameConversion", c.RelationNameConversion, &cfg.RelationNameConversion},
+	} {
+		if n.text == "" {
+			continue
+		}
+		t, err = template.New(n.name).Funcs(environ.FuncMap).Parse(n.text)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing "+n.name+" template")
+		}
+		*n.dest = t
+	}
+
+	if c.MySQLEnumNaming != "" {
+		t, err = template.New("MySQLEnumNaming").Funcs(environ.FuncMap).Parse(c.MySQLEnumNaming)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing MySQLEnumNaming template")
+		}
+		cfg.MySQLEnumNaming = t
+	}
+
+	if c.ArrayType != "" {
+		t, err = template.New("ArrayType").Funcs(environ.FuncMap).Parse(c.ArrayType)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing ArrayType template")
+		}
+		cfg.ArrayType = t
+	}
+
 	if len(c.TemplateEngine.CommandLine) != 0 {
 		for _, s := range c.TemplateEngine.CommandLine {
 			t, err = template.New("EngineCLI").Funcs(environ.FuncMap).Parse(s)
@@ -107,18 +258,30 @@ func Parse(env environ.Values, r io.Reader) (*run.Config, error) {
 		cfg.TemplateEngine.UseStdout = c.TemplateEngine.UseStdout
 	}
 
+	for path, name := range c.TargetTypeMaps {
+		if !hasTypeMap(c, name) {
+			return nil, errors.Errorf("TargetTypeMaps entry %q uses unknown type map %q", path, name)
+		}
+		_, table := c.TablePaths[path]
+		_, schema := c.SchemaPaths[path]
+		_, enum := c.EnumPaths[path]
+		if !table && !schema && !enum {
+			return nil, errors.Errorf("TargetTypeMaps entry %q is not an output path of TablePaths, SchemaPaths or EnumPaths", path)
+		}
+	}
+
 	useEngine := len(c.TemplateEngine.CommandLine) != 0
-	cfg.SchemaPaths, err = parseOutputTargets(c.SchemaPaths, useEngine)
+	cfg.SchemaPaths, err = parseOutputTargets(c.SchemaPaths, c.TargetTypeMaps, useEngine)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error parsing SchemaPaths")
 	}
 
-	cfg.TablePaths, err = parseOutputTargets(c.TablePaths, useEngine)
+	cfg.TablePaths, err = parseOutputTargets(c.TablePaths, c.TargetTypeMaps, useEngine)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error parsing TablePaths")
 	}
 
-	cfg.EnumPaths, err = parseOutputTargets(c.EnumPaths, useEngine)
+	cfg.EnumPaths, err = parseOutputTargets(c.EnumPaths, c.TargetTypeMaps, useEngine)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error parsing EnumPaths")
 	}
@@ -133,33 +296,40 @@ func Parse(env environ.Values, r io.Reader) (*run.Config, error) {
 	return cfg, nil
 }
 
-func getDriver(name string) (database.Driver, error) {
-	switch name {
-	case "postgres":
-		return postgres.PG{}, nil
-	case "mysql":
-		return mysql.MySQL{}, nil
-	default:
-		return nil, errors.Errorf("unknown database type: %v", name)
+// getDriver returns the driver registered for the database type, or failing
+// that, the external driver for it found in the plugin dirs.
+func getDriver(name string, pluginDirs []string) (database.Driver, error) {
+	if d, ok := database.Lookup(name); ok {
+		return d, nil
+	}
+	d, err := external.Find(pluginDirs, name)
+	if err != nil {
+		return nil, errors.Errorf("unknown database type: %v (built-in types are %s, and no external driver %s%v was found in PluginDirs)", name, strings.Join(database.Drivers(), ", "), external.Prefix, name)
 	}
+	return d, nil
 }
 
 // parseTables takes a list of tablenames in "<schema.>table" format and spits
 // out a map of schema to list of tables.  Tables with no schema apply to all
 // schemas.  Tables with a schema apply to only that schema.  Tables that
-// specify a schema not in the list of schemas given are an error.
+// specify a schema not in the list of schemas given are an error.  Names that
+// contain dots (or quotes) may be quoted with double quotes or backticks, e.g.
+// "my.schema"."my.table", and are then matched exactly.
 func parseTables(tables, schemas []string) (map[string][]string, error) {
 	out := make(map[string][]string, len(schemas))
 	for _, s := range schemas {
 		out[s] = nil
 	}
 	for _, t := range tables {
-		vals := strings.Split(t, ".")
+		vals, err := splitIdentifiers(t)
+		if err != nil {
+			return nil, err
+		}
 		switch len(vals) {
 		case 1:
 			// just the table name, so it goes for all schemas
 			for schema := range out {
-				out[schema] = append(out[schema], t)
+				out[schema] = append(out[schema], vals[0])
 			}
 		case 2:
 			// schema and table
@@ -170,14 +340,204 @@ func parseTables(tables, schemas []string) (map[string][]string, error) {
 			out[vals[0]] = append(list, vals[1])
 		default:
 			// too many periods... bad format
-			return nil, errors.Errorf(`badly formatted table: %q, should be just "table" or "table.schema"`, t)
+			return nil, errors.Errorf(`badly formatted table: %q, should be just "table" or "schema.table", quoting names that contain periods`, t)
 		}
 	}
 
 	return out, nil
 }
 
-func parseOutputTargets(vals map[string]string, usePath bool) ([]run.OutputTarget, error) {
+// parseTypeRules compiles the patterns of the type rules, and names the rules
+// that have no name after their index.
+func parseTypeRules(rules []TypeRule) ([]run.TypeRule, error) {
+	var out []run.TypeRule
+	for x, r := range rules {
+		rule := run.TypeRule{
+			Name:      r.Name,
+			Length:    r.Length,
+			Precision: r.Precision,
+			Scale:     r.Scale,
+			Array:     r.Array,
+			Nullable:  r.Nullable,
+			Type:      r.Type,
+			Types:     r.Types,
+		}
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("TypeRules[%d]", x)
+		}
+		if r.Type == "" && len(r.Types) == 0 {
+			return nil, errors.Errorf("type rule %s has no Type or Types", rule.Name)
+		}
+		for _, p := range []struct {
+			name    string
+			pattern string
+			dest    **regexp.Regexp
+		}{
+			{"DBType", r.DBType, &rule.DBType},
+			{"Schema", r.Schema, &rule.Schema},
+			{"Table", r.Table, &rule.Table},
+			{"Column", r.Column, &rule.Column},
+		} {
+			re, err := parsePattern(p.pattern)
+			if err != nil {
+				return nil, errors.WithMessage(err, "error parsing "+p.name+" of type rule "+rule.Name)
+			}
+			*p.dest = re
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// parsePattern compiles a type rule pattern into a regular expression.  A
+// pattern between slashes is a regular expression already.  Any other pattern
+// is a glob that must match the whole string, where * matches any run of
+// characters, ? any single character, and [abc] or [!abc] a character class.
+// An empty pattern returns nil, which matches anything.
+func parsePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if len(pattern) > 1 && pattern[0] == '/' && pattern[len(pattern)-1] == '/' {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(pattern)
+	for x := 0; x < len(runes); x++ {
+		switch r := runes[x]; r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := x + 1
+			for end < len(runes) && runes[end] != ']' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, errors.Errorf("unterminated character class in %q", pattern)
+			}
+			class := string(runes[x+1 : end])
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			x = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// parseOverrides takes the overrides keyed by "schema.table" or
+// "schema.table.column" and returns them keyed by the names they apply to.
+// Overrides for schemas not in the list of schemas, and table overrides of
+// anything but the name and params, are an error.
+func parseOverrides(overrides map[string]Override, schemas []string) (map[run.OverrideKey]run.Override, error) {
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]bool, len(schemas))
+	for _, s := range schemas {
+		known[s] = true
+	}
+	out := make(map[run.OverrideKey]run.Override, len(overrides))
+	for k, o := range overrides {
+		vals, err := splitIdentifiers(k)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error parsing Overrides")
+		}
+		var key run.OverrideKey
+		switch len(vals) {
+		case 2:
+			if o.Type != "" || len(o.Types) > 0 || o.Exclude || o.Nullable != nil || o.PrimaryKey {
+				return nil, errors.Errorf("override for table %q may only set Name and Params", k)
+			}
+			key = run.OverrideKey{Schema: vals[0], Table: vals[1]}
+		case 3:
+			key = run.OverrideKey{Schema: vals[0], Table: vals[1], Column: vals[2]}
+		default:
+			return nil, errors.Errorf(`badly formatted override: %q, should be "schema.table" or "schema.table.column", quoting names that contain periods`, k)
+		}
+		if !known[key.Schema] {
+			return nil, errors.Errorf("%q specified for overrides but schema %q not in schema list", k, key.Schema)
+		}
+		out[key] = run.Override{
+			Name:       o.Name,
+			Type:       o.Type,
+			Types:      o.Types,
+			Exclude:    o.Exclude,
+			Nullable:   o.Nullable,
+			PrimaryKey: o.PrimaryKey,
+			Params:     o.Params,
+		}
+	}
+	return out, nil
+}
+
+// hasTypeMap reports whether the config has a named type map with the given
+// name, in either TypeMaps or NullableTypeMaps.
+func hasTypeMap(c Config, name string) bool {
+	_, ok := c.TypeMaps[name]
+	_, nullable := c.NullableTypeMaps[name]
+	return ok || nullable
+}
+
+// splitIdentifiers splits a dotted name like schema.table into its
+// identifiers.  Each identifier may be quoted with double quotes or backticks,
+// in which case it may contain periods, and the quote character is escaped by
+// doubling it.
+func splitIdentifiers(name string) ([]string, error) {
+	var ids []string
+	for rest := name; ; {
+		var id string
+		if rest != "" && (rest[0] == '"' || rest[0] == '`') {
+			quote := rest[0]
+			var b strings.Builder
+			x := 1
+			for ; x < len(rest); x++ {
+				if rest[x] != quote {
+					b.WriteByte(rest[x])
+					continue
+				}
+				if x+1 < len(rest) && rest[x+1] == quote {
+					b.WriteByte(quote)
+					x++
+					continue
+				}
+				break
+			}
+			if x >= len(rest) {
+				return nil, errors.Errorf("badly formatted table: %q, unterminated quoted name", name)
+			}
+			id, rest = b.String(), rest[x+1:]
+			if rest != "" && rest[0] != '.' {
+				return nil, errors.Errorf("badly formatted table: %q, expected a period after quoted name %q", name, id)
+			}
+		} else {
+			x := strings.IndexByte(rest, '.')
+			if x == -1 {
+				x = len(rest)
+			}
+			id, rest = rest[:x], rest[x:]
+		}
+		if id == "" {
+			return nil, errors.Errorf("badly formatted table: %q, empty name", name)
+		}
+		ids = append(ids, id)
+		if rest == "" {
+			return ids, nil
+		}
+		rest = rest[1:]
+	}
+}
+
+// parseOutputTargets parses the "output-path" = "template-path" pairs into
+// output targets, which use the type maps named for their output paths.
+func parseOutputTargets(vals, typeMaps map[string]string, usePath bool) ([]run.OutputTarget, error) {
 	out := make([]run.OutputTarget, 0, len(vals))
 	for fnTempl, contTempl := range vals {
 		fn, err := template.New("filename").Funcs(environ.FuncMap).Parse(fnTempl)
@@ -190,7 +550,7 @@ func parseOutputTargets(vals map[string]string, usePath bool) ([]run.OutputTarge
 			if _, err := os.Stat(contTempl); err != nil {
 				return nil, errors.WithMessage(err, "error checking contents template")
 			}
-			out = append(out, run.OutputTarget{Filename: fn, ContentsPath: contTempl})
+			out = append(out, run.OutputTarget{Filename: fn, ContentsPath: contTempl, TypeMap: typeMaps[fnTempl]})
 			continue
 		}
 		b, err := ioutil.ReadFile(contTempl)
@@ -201,7 +561,7 @@ func parseOutputTargets(vals map[string]string, usePath bool) ([]run.OutputTarge
 		if err != nil {
 			return nil, errors.WithMessage(err, "error parsing contents template")
 		}
-		out = append(out, run.OutputTarget{Filename: fn, Contents: cont})
+		out = append(out, run.OutputTarget{Filename: fn, Contents: cont, TypeMap: typeMaps[fnTempl]})
 	}
 	return out, nil
 }
@@ -2,10 +2,15 @@ package cli
 
 import (
 	"bytes"
+	"io/ioutil"
 	"log"
+	"strings"
 	"testing"
+	"time"
 
+	"gnorm.org/gnorm/database/drivers/generic"
 	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run"
 	"gnorm.org/gnorm/run/data"
 
 	"github.com/BurntSushi/toml"
@@ -45,6 +50,31 @@ func TestParseTables(t *testing.T) {
 	}
 }
 
+func TestParseTablesQuoted(t *testing.T) {
+	tables := []string{`"my.schema"."My.Table"`, "`schema`.`back``tick`", `"say ""hi"""`}
+	schemas := []string{"my.schema", "schema"}
+
+	m, err := parseTables(tables, schemas)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{
+		"my.schema": {"My.Table", `say "hi"`},
+		"schema":    {"back`tick", `say "hi"`},
+	}
+	if diff := cmp.Diff(expected, m); diff != "" {
+		t.Errorf("unexpected tables:\n%s", diff)
+	}
+}
+
+func TestParseTablesBadFormat(t *testing.T) {
+	for _, table := range []string{"a.b.c", `"schema.table`, `"schema"table`, "schema.", ".table"} {
+		if _, err := parseTables([]string{table}, []string{"a", "schema"}); err == nil {
+			t.Errorf("expected error for table %q, but got none", table)
+		}
+	}
+}
+
 func TestParseConfig(t *testing.T) {
 	var stderr, stdout bytes.Buffer
 	env := environ.Values{
@@ -111,6 +141,447 @@ func TestParseGnormToml(t *testing.T) {
 	}
 }
 
+func TestParseTimeouts(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(timeouts string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+` + timeouts + `
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+`
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`ConnectTimeout = "10s"
+StatementTimeout = "1m30s"`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ConnectTimeout != 10*time.Second {
+		t.Errorf("expected ConnectTimeout of 10s, got %v", cfg.ConnectTimeout)
+	}
+	if cfg.StatementTimeout != 90*time.Second {
+		t.Errorf("expected StatementTimeout of 1m30s, got %v", cfg.StatementTimeout)
+	}
+
+	_, err = Parse(env, strings.NewReader(config(`ConnectTimeout = "soon"`)))
+	if err == nil {
+		t.Error("expected error for invalid duration, but got none")
+	}
+}
+
+func TestParseParallelism(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(parallelism string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+` + parallelism + `
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+`
+	}
+	tests := []struct {
+		config   string
+		expected int
+	}{
+		{"", defaultParallelism},
+		{"Parallelism = 1", 1},
+		{"Parallelism = 8", 8},
+	}
+	for _, test := range tests {
+		cfg, err := Parse(env, strings.NewReader(config(test.config)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Parallelism != test.expected {
+			t.Errorf("%q: expected Parallelism of %d, got %d", test.config, test.expected, cfg.Parallelism)
+		}
+	}
+
+	_, err := Parse(env, strings.NewReader(config("Parallelism = -1")))
+	if err == nil {
+		t.Error("expected error for negative Parallelism, but got none")
+	}
+}
+
+func TestParseSnapshot(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(dbType, snapshot string) string {
+		return `
+DBType = "` + dbType + `"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+` + snapshot + `
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+`
+	}
+	tests := []struct {
+		dbType   string
+		snapshot string
+		expected bool
+	}{
+		{"postgres", "", true},
+		{"postgres", "Snapshot = true", true},
+		{"postgres", "Snapshot = false", false},
+		{"mysql", "", false},
+		{"mysql", "Snapshot = true", true},
+	}
+	for _, test := range tests {
+		cfg, err := Parse(env, strings.NewReader(config(test.dbType, test.snapshot)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Snapshot != test.expected {
+			t.Errorf("%s %q: expected Snapshot %v, got %v", test.dbType, test.snapshot, test.expected, cfg.Snapshot)
+		}
+	}
+}
+
+func TestParseNameCollisions(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(extra string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+` + extra + `
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+`
+	}
+	cfg, err := Parse(env, strings.NewReader(config("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NameCollisions != run.CollisionWarn || len(cfg.ReservedWords) != 0 {
+		t.Errorf("expected NameCollisions %q and no reserved words by default, got %q and %q", run.CollisionWarn, cfg.NameCollisions, cfg.ReservedWords)
+	}
+
+	cfg, err = Parse(env, strings.NewReader(config(`NameCollisions = "Suffix"
+ReservedLanguages = ["go"]
+ReservedWords = ["Query"]`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.NameCollisions != run.CollisionSuffix {
+		t.Errorf("expected NameCollisions %q, got %q", run.CollisionSuffix, cfg.NameCollisions)
+	}
+	words := strings.Join(cfg.ReservedWords, " ")
+	if !strings.Contains(words, "func") || !strings.HasSuffix(words, "Query") {
+		t.Errorf("expected go's reserved words and Query, got %q", words)
+	}
+
+	for _, bad := range []string{`NameCollisions = "rename"`, `ReservedLanguages = ["cobol"]`} {
+		if _, err := Parse(env, strings.NewReader(config(bad))); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		matches []string
+		misses  []string
+	}{
+		{"*_id", []string{"author_id", "_id"}, []string{"author_ids", "id"}},
+		{"int?", []string{"int4", "int8"}, []string{"int", "int44"}},
+		{"int[48]", []string{"int4", "int8"}, []string{"int2"}},
+		{"int[!48]", []string{"int2"}, []string{"int4"}},
+		{"numeric(12,2)", []string{"numeric(12,2)"}, []string{"numeric(12,20)", "numeric122"}},
+		{"/^int[248]$/", []string{"int2"}, []string{"int"}},
+		{"/char/", []string{"varchar", "character varying"}, []string{"text"}},
+	}
+	for _, test := range tests {
+		re, err := parsePattern(test.pattern)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.pattern, err)
+			continue
+		}
+		for _, s := range test.matches {
+			if !re.MatchString(s) {
+				t.Errorf("%s: expected %q to match", test.pattern, s)
+			}
+		}
+		for _, s := range test.misses {
+			if re.MatchString(s) {
+				t.Errorf("%s: expected %q not to match", test.pattern, s)
+			}
+		}
+	}
+	if re, err := parsePattern(""); re != nil || err != nil {
+		t.Errorf("expected an empty pattern to be nil, got %v and %v", re, err)
+	}
+	for _, bad := range []string{"int[48", "/int(/"} {
+		if _, err := parsePattern(bad); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParseTypeRules(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(rules string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+` + rules
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`
+[[TypeRules]]
+Name = "ids"
+DBType = "uuid"
+Column = "*_id"
+Type = "ids.UUID"
+[[TypeRules]]
+DBType = "numeric"
+Precision = 12
+Array = false
+Type = "money.Amount"
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.TypeRules) != 2 {
+		t.Fatalf("expected 2 type rules, got %d", len(cfg.TypeRules))
+	}
+	ids, money := cfg.TypeRules[0], cfg.TypeRules[1]
+	if ids.Name != "ids" || ids.Type != "ids.UUID" || !ids.Column.MatchString("author_id") || ids.Table != nil {
+		t.Errorf("unexpected first rule %+v", ids)
+	}
+	if money.Name != "TypeRules[1]" || money.Precision == nil || *money.Precision != 12 || money.Array == nil || *money.Array || money.Scale != nil {
+		t.Errorf("unexpected second rule %+v", money)
+	}
+
+	if _, err := Parse(env, strings.NewReader(config("[[TypeRules]]\nDBType = \"uuid\""))); err == nil {
+		t.Error("expected error for a type rule without a Type, but got none")
+	}
+
+	cfg, err = Parse(env, strings.NewReader(config(`
+[[TypeRules]]
+DBType = "uuid"
+Types = { ts = "string" }
+[TypeMaps.ts]
+"uuid" = "string"
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := cfg.TypeRules[0]; r.Type != "" || r.Types["ts"] != "string" {
+		t.Errorf("expected a rule with only a ts type, got %+v", r)
+	}
+	if _, err := Parse(env, strings.NewReader(config("[[TypeRules]]\nDBType = \"uuid\"\nTypes = { go = \"string\" }"))); err == nil {
+		t.Error("expected error for a type rule with Types for an unknown type map, but got none")
+	}
+}
+
+func TestParseTargetTypeMaps(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(targets string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+"{{.Table}}.ts" = "testdata/table.tpl"
+
+[TypeMaps.ts]
+"integer" = "number"
+
+[NullableTypeMaps.ts]
+"integer" = "number | null"
+
+[TargetTypeMaps]
+` + targets
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`"{{.Table}}.ts" = "ts"`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TypeMaps["ts"]["integer"] != "number" || cfg.NullableTypeMaps["ts"]["integer"] != "number | null" {
+		t.Errorf("unexpected type maps %v and %v", cfg.TypeMaps, cfg.NullableTypeMaps)
+	}
+	maps := map[string]string{}
+	for _, target := range cfg.TablePaths {
+		var buf bytes.Buffer
+		if err := target.Filename.Execute(&buf, map[string]string{"Table": "t"}); err != nil {
+			t.Fatal(err)
+		}
+		maps[buf.String()] = target.TypeMap
+	}
+	if maps["t.go"] != "" || maps["t.ts"] != "ts" {
+		t.Errorf("expected only the .ts target to use the ts type map, got %v", maps)
+	}
+
+	for _, bad := range []string{`"{{.Table}}.ts" = "py"`, `"{{.Table}}.py" = "ts"`} {
+		if _, err := Parse(env, strings.NewReader(config(bad))); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParseTypeImports(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(imports string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+
+[TypeImports]
+` + imports
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`
+"uuid.UUID" = "github.com/google/uuid"
+"dec.Decimal" = { Path = "github.com/shopspring/decimal", Alias = "dec" }
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]data.Import{
+		"uuid.UUID":   {Path: "github.com/google/uuid"},
+		"dec.Decimal": {Path: "github.com/shopspring/decimal", Alias: "dec"},
+	}
+	if diff := cmp.Diff(expected, cfg.TypeImports); diff != "" {
+		t.Errorf("unexpected type imports (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{
+		`"dec.Decimal" = { Alias = "dec" }`,
+		`"dec.Decimal" = { Path = "github.com/shopspring/decimal", Name = "dec" }`,
+		`"dec.Decimal" = 1`,
+	} {
+		if _, err := Parse(env, strings.NewReader(config(bad))); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParseAnnotationPattern(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(extra string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+` + extra + `
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+`
+	}
+	cfg, err := Parse(env, strings.NewReader(config("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AnnotationPattern == nil || cfg.AnnotationPattern.String() != run.DefaultAnnotationPattern {
+		t.Errorf("expected the default AnnotationPattern %q, got %v", run.DefaultAnnotationPattern, cfg.AnnotationPattern)
+	}
+
+	cfg, err = Parse(env, strings.NewReader(config(`AnnotationPattern = '\[(\w+)=(\w+)\]'`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.AnnotationPattern.String(); got != `\[(\w+)=(\w+)\]` {
+		t.Errorf("expected the configured AnnotationPattern, got %q", got)
+	}
+
+	for _, bad := range []string{`AnnotationPattern = '@(\w+'`, `AnnotationPattern = '@\w+'`} {
+		if _, err := Parse(env, strings.NewReader(config(bad))); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParseOverrides(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(overrides string) string {
+		return `
+DBType = "postgres"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+` + overrides
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`
+[Overrides."public.users"]
+Name = "Account"
+[Overrides."public.users.email"]
+Type = "Email"
+Nullable = false
+[Overrides."public.users.email".Params]
+validate = "email"
+[Overrides.'public."my.view".id']
+PrimaryKey = true
+`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notNull := false
+	expected := map[run.OverrideKey]run.Override{
+		{Schema: "public", Table: "users"}:                  {Name: "Account"},
+		{Schema: "public", Table: "users", Column: "email"}: {Type: "Email", Nullable: &notNull, Params: map[string]interface{}{"validate": "email"}},
+		{Schema: "public", Table: "my.view", Column: "id"}:  {PrimaryKey: true},
+	}
+	if diff := cmp.Diff(expected, cfg.Overrides); diff != "" {
+		t.Errorf("unexpected overrides (-want +got):\n%s", diff)
+	}
+
+	for _, bad := range []string{
+		"[Overrides.\"public.users\"]\nExclude = true",
+		"[Overrides.\"other.users\"]\nName = \"Account\"",
+		"[Overrides.\"users\"]\nName = \"Account\"",
+		"[Overrides.\"public.users.email\"]\nTypes = { ts = \"Email\" }",
+	} {
+		if _, err := Parse(env, strings.NewReader(config(bad))); err == nil {
+			t.Errorf("%s: expected error, but got none", bad)
+		}
+	}
+}
+
+func TestParseGenericDriver(t *testing.T) {
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	config := func(generic string) string {
+		return `
+DBType = "generic"
+Schemas = ["public"]
+NameConversion = "{{.}}"
+
+[TablePaths]
+"{{.Table}}.go" = "testdata/table.tpl"
+
+[Generic]
+` + generic
+	}
+	cfg, err := Parse(env, strings.NewReader(config(`SQLDriver = "postgres"
+Placeholder = "$1"`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := generic.Driver{SQLDriver: "postgres", Placeholder: "$1"}
+	if cfg.Driver != expected {
+		t.Errorf("expected driver %#v, got %#v", expected, cfg.Driver)
+	}
+
+	if _, err := Parse(env, strings.NewReader(config(`Placeholder = "$1"`))); err == nil {
+		t.Error("expected error for missing SQLDriver, but got none")
+	}
+}
+
 func contains(list []string, s string) bool {
 	for x := range list {
 		if list[x] == s {
@@ -32,13 +32,50 @@ const sample = `# ConnStr is the connection string for the database.  Any enviro
 # Postgres example:
 ConnStr = "dbname=mydb host=127.0.0.1 sslmode=disable user=admin"
 
-# DBType holds the type of db you're connecting to.  Possible values are
-# "postgres" or "mysql".
+# DBType holds the type of db you're connecting to.  The built-in types are
+# "postgres", "mysql" and "generic" (see Generic below).  For any other type,
+# gnorm runs the external driver called gnorm-driver-<DBType> found in
+# PluginDirs.
 DBType = "postgres"
 
 # Schemas holds the names of schemas to generate code for.
 Schemas = ["public"]
 
+# ConnectTimeout limits how long opening each connection to the database may
+# take, as a duration such as "10s".  If not set, there is no limit.
+# ConnectTimeout = "10s"
+
+# StatementTimeout limits how long the database may spend running each query
+# that reads the schema, as a duration such as "30s".  If not set, there is no
+# limit.  On mysql this requires mysql 5.7.8 or later.
+# StatementTimeout = "30s"
+
+# Parallelism is the number of database connections the schema is read on.  The
+# catalog queries don't depend on each other, so they run concurrently, one per
+# connection at a time.  With Snapshot, on postgres all the connections share
+# one snapshot of the catalog, and mysql, which can't share snapshots, reads the
+# schema on a single connection.  Set it to 1 to run the queries one after the
+# other on a single connection.  The default is 4.
+# Parallelism = 4
+
+# Snapshot, if true, reads the whole schema in read-only transactions with
+# repeatable read isolation, so that all the queries see the same state of the
+# catalog even if it changes while gnorm reads it.  Mysql can't share a snapshot
+# between connections, so with Snapshot it reads the schema on a single
+# connection, ignoring Parallelism.  The default is true, except on mysql, where
+# it's false so that the schema is read on Parallelism connections.
+# Snapshot = false
+
+# Strict, if true, makes gnorm fail before generating anything if there are any
+# types not mapped by the TypeRules, TypeMap or NullableTypeMap, foreign keys or
+# other references to tables and columns that weren't read, skipped indexes,
+# overrides that match nothing, names that convert to an empty string, or names
+# that collide or are reserved words and aren't suffixed by NameCollisions.  The
+# error lists every problem with the schema.table.column it was found at.
+# Otherwise these are only logged with -verbose.  The --strict flag of gen and
+# preview turns it on too.
+# Strict = true
+
 # PluginDirs a list of paths that will be used for finding plugins.  The list
 # will be traversed in order, looking for a specifically named plugin. The first
 # plugin that is found will be the one used.
@@ -51,18 +88,86 @@ PluginDirs = ["plugins"]
 # the Name the PascalCase version, you'd use "{{pascal .}}".
 NameConversion = "{{.}}"
 
+# SchemaNameConversion, TableNameConversion, ColumnNameConversion,
+# EnumNameConversion, EnumValueNameConversion, IndexNameConversion,
+# ForeignKeyNameConversion and RelationNameConversion, if set, override
+# NameConversion for that kind of item.  Rather than the DB name, the "." value
+# of these templates has the fields .DBName, the DB name of the item, .Schema,
+# the schema it's in, and depending on the kind of item, .Table, the table being
+# named or that the item belongs to, .Column, the column being named, .Enum, the
+# enum being named or that the value belongs to, .ForeignKey, the foreign key
+# being named, and .Relation, the relation being named.  Everything that belongs
+# to an item is named after the item, so for example an enum value's template
+# can use .Enum.Name.  Items of other kinds (domains, composite types, sequences
+# and triggers) always use NameConversion.
+# TableNameConversion = "{{pascal (singular .DBName)}}"
+# EnumValueNameConversion = "{{.Enum.Name}}{{pascal .DBName}}"
+
+# NameCollisions is how converted names are handled that are the same as another
+# name in the same scope (the schemas, the tables and enums of a schema, the
+# columns of a table, or the values of an enum), or are reserved words.  It may
+# be "warn" (the default), which prints a warning to stderr, even without
+# -verbose, and leaves the names alone, "error", which fails with an error
+# naming the DB objects involved, or
+# "suffix", which appends a number to names that collide with a name earlier in
+# the scope and an underscore to reserved words.
+# NameCollisions = "suffix"
+
+# ReservedLanguages is a list of languages whose reserved words converted names
+# may not be.  The known languages are "go", "python" and "typescript".
+# ReservedLanguages = ["go"]
+
+# ReservedWords is a list of other words converted names may not be.
+# ReservedWords = ["Query"]
+
+# MySQLEnumNaming defines the DBName of mysql enums and sets, which belong to a
+# single column rather than being named types.  This is a template that may use
+# all the regular functions, and may reference the values .Schema, .Table and
+# .Column, containing the original names of the column's schema, table and
+# column.  The default is "{{.Column}}", which means two tables with a "status"
+# enum column will produce two enums named "status".
+# MySQLEnumNaming = "{{.Table}}_{{.Column}}"
+
+# MergeMySQLEnums, if true, merges mysql enums (or sets) in the same schema that
+# have identical lists of values into a single enum, which lists all the columns
+# that use it.  The merged enum is named after the first column that uses it.
+# MergeMySQLEnums = true
+
+# ArrayType defines the Type of array columns whose type isn't in the TypeMap or
+# NullableTypeMap with its array shape (e.g. "int4[]" or "int4[][]").  This is a
+# template that may use all the regular functions, and may reference the values
+# .Type, the element type mapped with the TypeMap, .DBType, the original element
+# type, .Dimensions, the number of dimensions of the array, .Nullable, and
+# .TypeMap, the name of the named type map the type is from, which is empty for
+# the TypeMap.  If not set, arrays are mapped by their element type.
+# ArrayType = "{{repeat \"[]\" .Dimensions}}{{.Type}}"
+
+# AnnotationPattern is a regular expression that finds the annotations in the
+# comments of tables, columns, enums, indexes and foreign keys, which are put in
+# their .Annotations, with the rest of the comment in their .Description.  Its
+# first subexpression is the key of an annotation, and its first other
+# subexpression that matches is the value.  An annotation without a value has
+# the value "true".  The default finds annotations like @deprecated,
+# @validate:email or @json:"-" that are at the start of the comment or after
+# whitespace, so a comment of 'Login email. @validate:email @json:"-"' has the
+# description "Login email." and the annotations validate = "email" and
+# json = "-".
+# AnnotationPattern = '(?:^|\s)@([\w.-]+)(?::(?:"([^"]*)"|(\S*)))?'
+
 # IncludeTables is a whitelist of tables to generate data for. Tables not
 # in this list will not be included in data geenrated by gnorm. You cannot
 # set IncludeTables if ExcludeTables is set.  By default, tables will be
 # included in all schemas.  To specify tables for a specific schema only,
-# use the schema.tablenmae format.
+# use the schema.tablenmae format.  Names that contain periods may be quoted
+# with double quotes or backticks, e.g. '"my.schema"."my.table"'.
 IncludeTables = []
 
 # ExcludeTables is a blacklist of tables to ignore while generating data.
 # All tables in a schema that are not in this list will be used for
 # generation. You cannot set ExcludeTables if IncludeTables is set.  By
 # default, tables will be excluded from all schemas.  To specify tables for
-# a specific schema only, use the schema.tablenmae format.
+# a specific schema only, use the schema.tablenmae format.  Names that contain
+# periods may be quoted as for IncludeTables.
 ExcludeTables = ["xyzzx"]
 
 # PostRun is a command with arguments that is run after each file is generated
@@ -142,9 +247,13 @@ NoOverwriteGlobs = ["*.perm.go"]
 # (generally types from your language for deserialization), specifically for
 # database columns that are nullable.  In the data sent to your template, this
 # is the mapping that translates Column.DBType into Column.Type.  If a DBType is
-# not in this mapping, Column.Type will be an empty string.  Note that because
-# of the way tables in TOML work, TypeMap and NullableTypeMap must be at the end
-# of your configuration file.
+# not in this mapping, Column.Type will be an empty string.  Keys may also be a
+# full column type (Column.ColumnType) such as "numeric(12,2)" or
+# "int(10) unsigned", a schema-qualified user-defined type such as
+# "billing.currency", or, for mysql, a type with its modifiers such as
+# "int unsigned" or "int unsigned zerofill".  The most specific key that exists
+# in the mapping is used.  Note that because of the way tables in TOML work,
+# TypeMap and NullableTypeMap must be at the end of your configuration file.
 # Example for mapping postgres types to Go types:
 [TypeMap]
 "timestamp with time zone" = "time.Time"
@@ -159,9 +268,10 @@ NoOverwriteGlobs = ["*.perm.go"]
 # (generally types from your language for deserialization), specifically for
 # database columns that are nullable.  In the data sent to your template, this
 # is the mapping that translates Column.DBType into Column.Type.  If a DBType is
-# not in this mapping, Column.Type will be an empty string.  Note that because
-# of the way tables in TOML work, TypeMap and NullableTypeMap must be at the end
-# of your configuration file.
+# not in this mapping, Column.Type will be an empty string.  Keys are looked up
+# the same way as for TypeMap.  Note that because of the way tables in TOML
+# work, TypeMap and NullableTypeMap must be at the end of your configuration
+# file.
 # Example for mapping postgres types to Go types:
 [NullableTypeMap]
 "timestamp with time zone" = "pq.NullTime"
@@ -172,6 +282,45 @@ NoOverwriteGlobs = ["*.perm.go"]
 "integer" = "sql.NullInt64"
 "numeric" = "sql.NullFloat64"
 
+# TypeMaps are named type maps, for generating code in more than one language
+# from the same schema.  Each maps database type names to the types of one
+# language, looked up the same way as for TypeMap.  In the data sent to your
+# template, the type from each map is in Column.Types under the map's name, e.g.
+# Column.Types.ts.  NullableTypeMaps are the named maps for nullable columns.
+# The named maps are used the same way as the TypeMap, after the TypeRules and
+# Overrides and with the ArrayType, whose Types, if they have one for the map,
+# replace their Type.  Note that because of the way tables in TOML work,
+# TypeMaps and NullableTypeMaps must be at the end of your configuration file.
+# [TypeMaps.ts]
+    # "text" = "string"
+    # "integer" = "number"
+# [NullableTypeMaps.ts]
+    # "text" = "string | null"
+    # "integer" = "number | null"
+
+# TargetTypeMaps is a map of output paths of TablePaths, SchemaPaths or
+# EnumPaths to the name of the type map whose types are the Column.Type of
+# every column, and whose imports are the Table.Imports and Schema.Imports, in
+# the data that output is rendered with.  Output paths not listed here use
+# TypeMap and NullableTypeMap.
+# [TargetTypeMaps]
+    # "{{.Schema}}/tables/{{.Table}}.ts" = "ts"
+
+# TypeImports is a mapping of the types columns are mapped to, by any of the
+# type maps, TypeRules or Overrides, to the import path of the package that
+# declares them.  A key may also be the package qualifier of the types, like
+# "sql" for all of sql.NullString, sql.NullInt64 and so on.  To import the
+# package with an alias, use a table with Path and Alias.  Types are read as Go
+# type expressions, so a type like map[uuid.UUID]dec.Decimal or []*time.Time
+# needs the imports of every qualified type in it; a type that isn't valid Go
+# is looked up as a whole.  In the data sent to your template, the imports
+# needed by the columns of a table, or of all the tables in a schema, are in
+# Table.Imports and Schema.Imports, sorted and without duplicates.
+# [TypeImports]
+    # "time.Time" = "time"
+    # "uuid.UUID" = "github.com/google/uuid"
+    # "sql" = "database/sql"
+    # "dec.Decimal" = { Path = "github.com/shopspring/decimal", Alias = "dec" }
 
 # Params contains any data you may want to pass to your templates.  This is a
 # good way to make templates reusable with different configuration values for
@@ -195,5 +344,65 @@ mySpecialValue = "some value"
     # UseStdin = false
 
     # If true, the standard output of the tool will be written to the target file.
-    # UseStdout = false`
+    # UseStdout = false
+
+# Generic configures the generic driver, used when DBType is "generic", which
+# reads the schema from the standard information_schema views using any
+# database/sql driver compiled into gnorm.
+# [Generic]
+    # SQLDriver is the name of the database/sql driver to connect with, either
+    # "postgres" (lib/pq) or "mysql" (go-sql-driver/mysql).
+    # SQLDriver = "postgres"
+
+    # Placeholder is the style of query parameter placeholders the database
+    # uses, either "?" (the default) or "$1".
+    # Placeholder = "$1"
+
+# TypeRules is a list of rules that map the types of columns by more than their
+# database type, checked in order before the TypeMap and NullableTypeMap.  The
+# first rule that matches a column sets its Type, and the rule's name is the
+# column's TypeSource, which preview --format types shows.  A rule matches a
+# column if it matches everything set in the rule: DBType, Schema, Table and
+# Column are globs (*, ? and [abc]) matched against the whole name, or regular
+# expressions if written between slashes; Length, Precision and Scale match
+# exactly; and Array and Nullable match whether the column is an array or
+# nullable.  Since Type is the type of the whole column, arrays only match rules
+# with Array = true.  Name defaults to TypeRules[n], where n is the rule's index.  Types
+# holds the rule's types in the named TypeMaps, which replace its Type in those
+# maps; a rule with Types but no Type only applies to those maps.
+# [[TypeRules]]
+    # Name = "ids"
+    # DBType = "uuid"
+    # Column = "*_id"
+    # Type = "ids.UUID"
+    # Types = { ts = "string" }
+# [[TypeRules]]
+    # DBType = "/^numeric/"
+    # Precision = 12
+    # Scale = 2
+    # Nullable = false
+    # Type = "money.Amount"
+
+# Overrides adjust single tables and columns without changing the database.
+# The keys are "schema.table" for a table or "schema.table.column" for a
+# column, quoting names that contain periods as for IncludeTables.  Tables may
+# only set Name and Params.
+#
+# Name replaces the converted name, Type replaces the mapped type of a column,
+# Types replaces it in the named TypeMaps, Exclude leaves a column (and any
+# index or foreign key that includes it, or foreign key that references it) out
+# of the data, Nullable forces whether a column is nullable before its type is
+# mapped, PrimaryKey makes a column part of the primary key (useful for views),
+# and Params is available in templates as the table's or column's .Params
+# value.
+# [Overrides."public.users"]
+    # Name = "Account"
+# [Overrides."public.users.password_hash"]
+    # Exclude = true
+# [Overrides."public.active_users.id"]
+    # PrimaryKey = true
+    # Nullable = false
+# [Overrides."public.users.email".Params]
+    # validate = "email"
+`
 // [[[end]]]
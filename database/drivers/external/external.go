@@ -0,0 +1,146 @@
+// Package external implements a driver that reads the database schema by
+// running an external executable, so that gnorm can support databases it has
+// no built-in driver for.
+//
+// The executable is sent a Request as JSON on its standard input, and must
+// write the database.Info for the requested schemas as JSON to its standard
+// output and exit with a zero status.  Anything it writes to standard error is
+// logged.  If it exits with a non-zero status, its standard error is used as
+// the error message.
+package external // import "gnorm.org/gnorm/database/drivers/external"
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gnorm.org/gnorm/database"
+)
+
+// Prefix is prepended to the DBType to get the name of the executable of the
+// external driver for it, e.g. gnorm-driver-oracle for a DBType of "oracle".
+const Prefix = "gnorm-driver-"
+
+// Request holds the options for reading the schema that are sent to an
+// external driver.
+type Request struct {
+	// ConnStr is the connection string for the database.
+	ConnStr string `json:"connStr"`
+
+	// Schemas holds the names of the schemas to read.
+	Schemas []string `json:"schemas"`
+
+	// IncludeTables is a map of schema names to the only tables to read in
+	// that schema.  Schemas with no tables listed have no restriction.
+	IncludeTables map[string][]string `json:"includeTables,omitempty"`
+
+	// ExcludeTables is a map of schema names to tables not to read in that
+	// schema.
+	ExcludeTables map[string][]string `json:"excludeTables,omitempty"`
+
+	// ConnectTimeout and StatementTimeout are the timeouts for connecting and
+	// running each query, as Go durations such as "10s".  They are empty if
+	// there's no limit.
+	ConnectTimeout   string `json:"connectTimeout,omitempty"`
+	StatementTimeout string `json:"statementTimeout,omitempty"`
+
+	// Snapshot is true if the schema should be read in a single read-only
+	// transaction with snapshot isolation, if the database supports it.
+	Snapshot bool `json:"snapshot"`
+
+	// Parallelism is the number of connections the driver may use to run its
+	// queries concurrently.  It is left out if the queries should run one at a
+	// time.
+	Parallelism int `json:"parallelism,omitempty"`
+}
+
+// Driver reads the database schema by running an external executable.
+type Driver struct {
+	// Path is the path of the driver's executable.
+	Path string
+}
+
+// Find looks for the executable of the external driver for the given DBType
+// in each of dirs in turn, and returns a Driver for the first one found.
+func Find(dirs []string, dbType string) (Driver, error) {
+	for _, dir := range dirs {
+		p, err := exec.LookPath(filepath.Join(dir, Prefix+dbType))
+		if err == nil {
+			return Driver{Path: p}, nil
+		}
+	}
+	return Driver{}, errors.Errorf("no external driver %s%s found in plugin dirs %v", Prefix, dbType, dirs)
+}
+
+// Parse runs the driver's executable to read the database schema.  The
+// executable is killed if the context is cancelled.  Tables the executable
+// returns that are filtered out by the options are dropped.
+func (d Driver) Parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
+	req := Request{
+		ConnStr:       conn,
+		Schemas:       opts.Schemas,
+		IncludeTables: opts.IncludeTables,
+		ExcludeTables: opts.ExcludeTables,
+		Snapshot:      opts.Snapshot,
+	}
+	if opts.Parallelism > 1 {
+		req.Parallelism = opts.Parallelism
+	}
+	if opts.ConnectTimeout > 0 {
+		req.ConnectTimeout = opts.ConnectTimeout.String()
+	}
+	if opts.StatementTimeout > 0 {
+		req.StatementTimeout = opts.StatementTimeout.String()
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	log.Println("running external driver", d.Path)
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.Path)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	s := bufio.NewScanner(&stderr)
+	var lines []string
+	for s.Scan() {
+		log.Println(d.Path+":", s.Text())
+		lines = append(lines, s.Text())
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, errors.Wrapf(err, "external driver %s failed: %s", d.Path, strings.Join(lines, "\n"))
+	}
+
+	info := &database.Info{}
+	if err := json.Unmarshal(stdout.Bytes(), info); err != nil {
+		return nil, errors.Wrapf(err, "error decoding schema from external driver %s", d.Path)
+	}
+	filterTables(info, opts)
+	return info, nil
+}
+
+// filterTables removes the tables the options filter out from the info.
+func filterTables(info *database.Info, opts database.Options) {
+	for _, s := range info.Schemas {
+		tables := s.Tables[:0]
+		for _, t := range s.Tables {
+			if opts.IncludeTable(s.Name, t.Name) {
+				tables = append(tables, t)
+			}
+		}
+		s.Tables = tables
+	}
+}
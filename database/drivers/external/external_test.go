@@ -0,0 +1,114 @@
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gnorm.org/gnorm/database"
+)
+
+// TestMain makes the test binary act as an external driver when
+// GNORM_TEST_DRIVER is set.  It echoes the request it received in the comment
+// of the first table.
+func TestMain(m *testing.M) {
+	switch os.Getenv("GNORM_TEST_DRIVER") {
+	case "":
+		os.Exit(m.Run())
+	case "fail":
+		fmt.Fprintln(os.Stderr, "can't connect")
+		os.Exit(1)
+	default:
+		b, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Fprintln(os.Stderr, "reading schemas")
+		info := &database.Info{Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{
+				{Name: "users", Comment: string(b)},
+				{Name: "secrets"},
+			},
+		}}}
+		if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	os.Setenv("GNORM_TEST_DRIVER", "ok")
+	defer os.Unsetenv("GNORM_TEST_DRIVER")
+
+	var logs bytes.Buffer
+	d := Driver{Path: os.Args[0]}
+	info, err := d.Parse(context.Background(), log.New(&logs, "", 0), "conn", database.Options{
+		Schemas:       []string{"public"},
+		FilterTables:  func(schema, table string) bool { return table != "secrets" },
+		ExcludeTables: map[string][]string{"public": {"secrets"}},
+		Snapshot:      true,
+		Parallelism:   4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(info.Schemas) != 1 || len(info.Schemas[0].Tables) != 1 {
+		t.Fatalf("expected one schema with the one unfiltered table, got %#v", info.Schemas)
+	}
+
+	var req Request
+	if err := json.Unmarshal([]byte(info.Schemas[0].Tables[0].Comment), &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.ConnStr != "conn" || len(req.Schemas) != 1 || req.Schemas[0] != "public" || !req.Snapshot || req.Parallelism != 4 || len(req.ExcludeTables["public"]) != 1 {
+		t.Errorf("driver got unexpected request %#v", req)
+	}
+	if !strings.Contains(logs.String(), "reading schemas") {
+		t.Errorf("expected driver's stderr to be logged, got %q", logs.String())
+	}
+}
+
+func TestParseFailure(t *testing.T) {
+	os.Setenv("GNORM_TEST_DRIVER", "fail")
+	defer os.Unsetenv("GNORM_TEST_DRIVER")
+
+	d := Driver{Path: os.Args[0]}
+	_, err := d.Parse(context.Background(), log.New(ioutil.Discard, "", 0), "conn", database.Options{})
+	if err == nil {
+		t.Fatal("expected error from failing driver, but got none")
+	}
+	if !strings.Contains(err.Error(), "can't connect") {
+		t.Errorf("expected error to include driver's stderr, got %q", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnorm-driver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, Prefix+"test")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Find([]string{"missing", dir}, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Path != path {
+		t.Errorf("expected driver at %q, got %q", path, d.Path)
+	}
+	if _, err := Find([]string{dir}, "other"); err == nil {
+		t.Error("expected error for missing driver, but got none")
+	}
+}
@@ -0,0 +1,343 @@
+// Package generic implements a driver that reads the database schema using
+// only the ANSI standard information_schema views, through any database/sql
+// driver.  It gives a baseline for databases gnorm has no dedicated driver
+// for, like CockroachDB, TiDB or MariaDB, but doesn't know about any
+// database-specific features such as enums, comments or triggers.  Only the
+// database/sql drivers compiled into gnorm may be used, which are lib/pq as
+// "postgres" and go-sql-driver/mysql as "mysql".
+package generic // import "gnorm.org/gnorm/database/drivers/generic"
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"gnorm.org/gnorm/database"
+)
+
+// The placeholder styles for query parameters of the sql drivers compiled
+// into gnorm.
+const (
+	PlaceholderQuestion = "?"  // ?, e.g. mysql
+	PlaceholderDollar   = "$1" // $1, $2, e.g. postgres
+)
+
+// Driver reads the database schema from information_schema.
+type Driver struct {
+	// SQLDriver is the name of the database/sql driver used to connect.  The
+	// driver must be registered with database/sql.
+	SQLDriver string
+
+	// Placeholder is the placeholder style for query parameters the sql
+	// driver uses, one of the Placeholder constants.
+	Placeholder string
+}
+
+func init() {
+	database.Register("generic", Driver{})
+}
+
+// Configure returns the driver for the SQLDriver and Placeholder settings.
+func (Driver) Configure(settings map[string]string) (database.Driver, error) {
+	return New(settings["SQLDriver"], settings["Placeholder"])
+}
+
+// New returns a driver that connects using the named database/sql driver,
+// which uses the given placeholder style.  An empty placeholder style means
+// PlaceholderQuestion.
+func New(sqlDriver, placeholder string) (Driver, error) {
+	if sqlDriver == "" {
+		return Driver{}, errors.New("no database/sql driver specified for the generic driver")
+	}
+	switch placeholder {
+	case "":
+		placeholder = PlaceholderQuestion
+	case PlaceholderQuestion, PlaceholderDollar:
+	default:
+		return Driver{}, errors.Errorf("unknown placeholder style %q, expected %q or %q", placeholder, PlaceholderQuestion, PlaceholderDollar)
+	}
+	return Driver{SQLDriver: sqlDriver, Placeholder: placeholder}, nil
+}
+
+// params returns the placeholders and values for a query that filters on the
+// given schema names.
+func (d Driver) params(schemas []string) (string, []interface{}) {
+	spots := make([]string, len(schemas))
+	vals := make([]interface{}, len(schemas))
+	for x := range schemas {
+		switch d.Placeholder {
+		case PlaceholderDollar:
+			spots[x] = fmt.Sprintf("$%d", x+1)
+		default:
+			spots[x] = "?"
+		}
+		vals[x] = schemas[x]
+	}
+	return strings.Join(spots, ", "), vals
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// conn runs the queries that read the schema.
+type conn struct {
+	q                querier
+	driver           Driver
+	statementTimeout func(context.Context) (context.Context, context.CancelFunc)
+}
+
+// query runs a query filtered on the schema names, and calls scan for each
+// row.
+func (c conn) query(ctx context.Context, what, q string, schemas []string, scan func(*sql.Rows) error) error {
+	ctx, cancel := c.statementTimeout(ctx)
+	defer cancel()
+	spots, vals := c.driver.params(schemas)
+	rows, err := c.q.QueryContext(ctx, fmt.Sprintf(q, spots), vals...)
+	if err != nil {
+		return errors.WithMessage(err, "error querying "+what)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return errors.WithMessage(err, "error scanning "+what)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.WithMessage(err, "error reading "+what)
+	}
+	return nil
+}
+
+// Parse reads the schemas from information_schema.
+func (d Driver) Parse(ctx context.Context, log *log.Logger, connStr string, opts database.Options) (*database.Info, error) {
+	log.Printf("connecting to %s with DSN %s", d.SQLDriver, connStr)
+	db, err := sql.Open(d.SQLDriver, connStr)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer db.Close()
+
+	pctx := ctx
+	if opts.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		pctx, cancel = context.WithTimeout(ctx, opts.ConnectTimeout)
+		defer cancel()
+	}
+	if err := db.PingContext(pctx); err != nil {
+		return nil, errors.WithMessage(err, "error connecting to "+d.SQLDriver)
+	}
+
+	c := conn{q: db, driver: d}
+	// there's no portable way to set a statement timeout on the server, so we
+	// give each query its own deadline instead.
+	c.statementTimeout = func(ctx context.Context) (context.Context, context.CancelFunc) {
+		if opts.StatementTimeout > 0 {
+			return context.WithTimeout(ctx, opts.StatementTimeout)
+		}
+		return context.WithCancel(ctx)
+	}
+	if opts.Snapshot {
+		tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			log.Printf("%s doesn't support read-only repeatable read transactions, reading without one: %v", d.SQLDriver, err)
+		} else {
+			// we only ever read, so there's nothing to commit.
+			defer tx.Rollback()
+			c.q = tx
+		}
+	}
+	return c.parse(ctx, log, opts)
+}
+
+// tableKey identifies a table by its schema and name.
+type tableKey struct {
+	schema, table string
+}
+
+func (c conn) parse(ctx context.Context, log *log.Logger, opts database.Options) (*database.Info, error) {
+	log.Println("querying table schemas for", opts.Schemas)
+	schemas := make(map[string][]*database.Table, len(opts.Schemas))
+	tables := map[tableKey]*database.Table{}
+	err := c.query(ctx, "tables", `
+	SELECT table_schema, table_name, table_type
+	FROM information_schema.tables
+	WHERE table_schema IN (%s)
+	ORDER BY table_schema, table_name`, opts.Schemas, func(rows *sql.Rows) error {
+		var schema string
+		t := &database.Table{}
+		if err := rows.Scan(&schema, &t.Name, &t.Type); err != nil {
+			return err
+		}
+		if !opts.IncludeTable(schema, t.Name) {
+			log.Printf("skipping filtered-out table %v.%v", schema, t.Name)
+			return nil
+		}
+		t.IsView = strings.Contains(strings.ToUpper(t.Type), "VIEW")
+		t.Kind = database.KindTable
+		if t.IsView {
+			t.Kind = database.KindView
+		}
+		t.IsInsertable = !t.IsView
+		schemas[schema] = append(schemas[schema], t)
+		tables[tableKey{schema, t.Name}] = t
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("found %v tables", len(tables))
+
+	columns := map[tableKey]map[string]*database.Column{}
+	err = c.query(ctx, "columns", `
+	SELECT
+		table_schema,
+		table_name,
+		column_name,
+		ordinal_position,
+		column_default,
+		is_nullable,
+		data_type,
+		character_maximum_length,
+		numeric_precision,
+		numeric_scale
+	FROM information_schema.columns
+	WHERE table_schema IN (%s)
+	ORDER BY table_schema, table_name, ordinal_position`, opts.Schemas, func(rows *sql.Rows) error {
+		var key tableKey
+		var def sql.NullString
+		var nullable string
+		var length, precision, scale sql.NullInt64
+		col := &database.Column{}
+		if err := rows.Scan(&key.schema, &key.table, &col.Name, &col.Ordinal, &def, &nullable, &col.Type, &length, &precision, &scale); err != nil {
+			return err
+		}
+		t, ok := tables[key]
+		if !ok {
+			return nil
+		}
+		col.HasDefault = def.Valid
+		col.Nullable = strings.EqualFold(nullable, "YES")
+		col.Length = int(length.Int64)
+		col.Precision = int(precision.Int64)
+		col.Scale = int(scale.Int64)
+		t.Columns = append(t.Columns, col)
+		if columns[key] == nil {
+			columns[key] = map[string]*database.Column{}
+		}
+		columns[key][col.Name] = col
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// information_schema has no indexes, but primary keys and unique
+	// constraints are backed by unique indexes in most databases, so we report
+	// unique constraints as indexes.
+	indexes := map[tableKey]*database.Index{}
+	err = c.query(ctx, "key constraints", `
+	SELECT
+		tc.table_schema,
+		tc.table_name,
+		tc.constraint_name,
+		tc.constraint_type,
+		kcu.column_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_schema = tc.constraint_schema
+		AND kcu.constraint_name = tc.constraint_name
+		AND kcu.table_schema = tc.table_schema
+		AND kcu.table_name = tc.table_name
+	WHERE tc.constraint_type IN ('PRIMARY KEY', 'UNIQUE')
+	AND tc.table_schema IN (%s)
+	ORDER BY tc.table_schema, tc.table_name, tc.constraint_name, kcu.ordinal_position`, opts.Schemas, func(rows *sql.Rows) error {
+		var key tableKey
+		var name, typ, column string
+		if err := rows.Scan(&key.schema, &key.table, &name, &typ, &column); err != nil {
+			return err
+		}
+		t, ok := tables[key]
+		if !ok {
+			return nil
+		}
+		col, ok := columns[key][column]
+		if !ok {
+			log.Printf("Should be impossible: constraint %q references unknown column %q of table %q", name, column, key.table)
+			return nil
+		}
+		if typ == "PRIMARY KEY" {
+			col.IsPrimaryKey = true
+		}
+		// constraint names are only unique per table in some databases.
+		ikey := tableKey{key.schema, key.table + "." + name}
+		index, ok := indexes[ikey]
+		if !ok {
+			index = &database.Index{Name: name, IsUnique: true}
+			indexes[ikey] = index
+			t.Indexes = append(t.Indexes, index)
+		}
+		index.Columns = append(index.Columns, col)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the standard says constraint names are unique within a schema, so the
+	// referenced unique constraint is identified by its schema and name.
+	// Databases where they're only unique per table (like mysql, where every
+	// primary key is called PRIMARY) can't be read reliably this way, so we
+	// keep only the first reference for each column.
+	err = c.query(ctx, "foreign keys", `
+	SELECT
+		kcu.table_schema,
+		kcu.table_name,
+		kcu.column_name,
+		rc.constraint_name,
+		kcu.position_in_unique_constraint,
+		ref.table_schema,
+		ref.table_name,
+		ref.column_name
+	FROM information_schema.referential_constraints rc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_schema = rc.constraint_schema
+		AND kcu.constraint_name = rc.constraint_name
+	JOIN information_schema.key_column_usage ref
+		ON ref.constraint_schema = rc.unique_constraint_schema
+		AND ref.constraint_name = rc.unique_constraint_name
+		AND ref.ordinal_position = kcu.position_in_unique_constraint
+	WHERE rc.constraint_schema IN (%s)
+	ORDER BY kcu.table_schema, kcu.table_name, rc.constraint_name, kcu.ordinal_position`, opts.Schemas, func(rows *sql.Rows) error {
+		fk := &database.ForeignKey{}
+		var position sql.NullInt64
+		if err := rows.Scan(&fk.SchemaName, &fk.TableName, &fk.ColumnName, &fk.Name, &position, &fk.ForeignSchemaName, &fk.ForeignTableName, &fk.ForeignColumnName); err != nil {
+			return err
+		}
+		fk.UniqueConstraintPosition = int(position.Int64)
+		col, ok := columns[tableKey{fk.SchemaName, fk.TableName}][fk.ColumnName]
+		if !ok || col.IsForeignKey {
+			return nil
+		}
+		col.IsForeignKey = true
+		col.ForeignKey = fk
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := &database.Info{Schemas: make([]*database.Schema, 0, len(opts.Schemas))}
+	for _, schema := range opts.Schemas {
+		res.Schemas = append(res.Schemas, &database.Schema{
+			Name:   schema,
+			Tables: schemas[schema],
+		})
+	}
+	return res, nil
+}
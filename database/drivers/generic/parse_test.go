@@ -0,0 +1,65 @@
+package generic
+
+import (
+	"reflect"
+	"testing"
+
+	"gnorm.org/gnorm/database"
+)
+
+func TestNew(t *testing.T) {
+	d, err := New("postgres", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Placeholder != PlaceholderQuestion {
+		t.Errorf("expected default placeholder %q, got %q", PlaceholderQuestion, d.Placeholder)
+	}
+	if _, err := New("", "?"); err == nil {
+		t.Error("expected error for missing sql driver, but got none")
+	}
+	if _, err := New("postgres", ":1"); err == nil {
+		t.Error("expected error for unknown placeholder style, but got none")
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	d, ok := database.Lookup("generic")
+	if !ok {
+		t.Fatal("expected the generic driver to be registered")
+	}
+	c, ok := d.(database.Configurer)
+	if !ok {
+		t.Fatal("expected the generic driver to be configurable")
+	}
+	d, err := c.Configure(map[string]string{"SQLDriver": "mysql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Driver{SQLDriver: "mysql", Placeholder: PlaceholderQuestion}
+	if d != expected {
+		t.Errorf("expected driver %#v, got %#v", expected, d)
+	}
+	if _, err := c.Configure(nil); err == nil {
+		t.Error("expected error for missing sql driver, but got none")
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		placeholder string
+		spots       string
+	}{
+		{PlaceholderQuestion, "?, ?"},
+		{PlaceholderDollar, "$1, $2"},
+	}
+	for _, tt := range tests {
+		spots, vals := Driver{Placeholder: tt.placeholder}.params([]string{"a", "b"})
+		if spots != tt.spots {
+			t.Errorf("placeholder %q: expected %q, got %q", tt.placeholder, tt.spots, spots)
+		}
+		if !reflect.DeepEqual(vals, []interface{}{"a", "b"}) {
+			t.Errorf("placeholder %q: expected values [a b], got %v", tt.placeholder, vals)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+// Package pool runs the catalog queries of the sql drivers concurrently, on a
+// pool of read-only transactions.  Each driver only starts the transactions
+// its own way.
+package pool // import "gnorm.org/gnorm/database/drivers/internal/pool"
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+)
+
+// DB is what the queries run on.  It's the same as the DB of the drivers'
+// gnorm packages.
+type DB interface {
+	Exec(string, ...interface{}) (sql.Result, error)
+	Query(string, ...interface{}) (*sql.Rows, error)
+	QueryRow(string, ...interface{}) *sql.Row
+}
+
+// Tx is a read-only transaction the schema is read in, like *sql.Tx.
+type Tx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	Rollback() error
+}
+
+// rollbackTimeout limits how long rolling back a ConnTx may take, so that a
+// dead connection can't hang gnorm after the queries are done.
+const rollbackTimeout = 10 * time.Second
+
+// ConnTx is a transaction started with statements on a dedicated connection,
+// for databases whose transaction options database/sql doesn't support.
+type ConnTx struct {
+	*sql.Conn
+}
+
+// Rollback rolls back the transaction and releases the connection.
+func (c ConnTx) Rollback() error {
+	// the context of the queries may be done by now, so the rollback can't
+	// use it.
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	_, err := c.ExecContext(ctx, "ROLLBACK")
+	if cerr := c.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Conn returns a dedicated connection to the database, connecting within
+// timeout unless it's zero.  The timeout only limits connecting, not the use
+// of the connection.
+func Conn(ctx context.Context, sqldb *sql.DB, timeout time.Duration) (*sql.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := sqldb.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// txDB runs queries in a transaction with the parse context, so that they're
+// cancelled along with it.
+type txDB struct {
+	ctx context.Context
+	tx  Tx
+}
+
+func (t txDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(t.ctx, query, args...)
+}
+
+func (t txDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(t.ctx, query, args...)
+}
+
+func (t txDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(t.ctx, query, args...)
+}
+
+// Open starts n transactions with begin, one after the other, for the queries
+// to run in with ctx.  The returned function rolls back the transactions; we
+// only ever read, so there's nothing to commit.
+func Open(ctx context.Context, log *log.Logger, n int, begin func() (Tx, error)) ([]DB, func(), error) {
+	if n < 1 {
+		n = 1
+	}
+	var txs []Tx
+	rollback := func() {
+		for _, tx := range txs {
+			tx.Rollback()
+		}
+	}
+	for len(txs) < n {
+		tx, err := begin()
+		if err != nil {
+			rollback()
+			return nil, nil, err
+		}
+		txs = append(txs, tx)
+	}
+	if n > 1 {
+		log.Printf("reading the schema on %d connections", n)
+	}
+	pool := make([]DB, len(txs))
+	for x, tx := range txs {
+		pool[x] = txDB{ctx: ctx, tx: tx}
+	}
+	return pool, rollback, nil
+}
+
+// Query is one of the catalog queries that read the schema.  The queries
+// don't depend on each other, so they may run concurrently, and their results
+// are merged once they've all finished.
+type Query struct {
+	Name string
+	Run  func(db DB) error
+}
+
+// Run runs the queries on the connections of the pool, one query at a time
+// per connection, and waits for them to finish.  The first query that fails
+// stops the rest: cancel is called to interrupt the ones running, and its
+// error is returned.
+func Run(log *log.Logger, pool []DB, cancel func(), queries []Query) error {
+	jobs := make(chan Query, len(queries))
+	for _, q := range queries {
+		jobs <- q
+	}
+	close(jobs)
+
+	var mu sync.Mutex
+	var first error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return first != nil
+	}
+	var wg sync.WaitGroup
+	for _, db := range pool {
+		wg.Add(1)
+		go func(db DB) {
+			defer wg.Done()
+			for q := range jobs {
+				if failed() {
+					return
+				}
+				start := time.Now()
+				err := q.Run(db)
+				log.Printf("%s query took %v", q.Name, time.Since(start))
+				if err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+						cancel()
+					}
+					mu.Unlock()
+					return
+				}
+			}
+		}(db)
+	}
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	var logs bytes.Buffer
+	pool := []DB{txDB{}, txDB{}}
+	// each query waits for the other to start, so they only finish if they
+	// run concurrently.
+	var started sync.WaitGroup
+	started.Add(2)
+	wait := func(DB) error {
+		started.Done()
+		done := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-time.After(5 * time.Second):
+			return errors.New("queries didn't run concurrently")
+		}
+	}
+	err := Run(log.New(&logs, "", 0), pool, func() {}, []Query{
+		{"first", wait},
+		{"second", wait},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs.String(), "first query took") || !strings.Contains(logs.String(), "second query took") {
+		t.Errorf("expected timing of each query to be logged, got %q", logs.String())
+	}
+}
+
+func TestRunError(t *testing.T) {
+	expected := errors.New("boom")
+	cancelled := false
+	ran := false
+	err := Run(log.New(ioutil.Discard, "", 0), []DB{txDB{}}, func() { cancelled = true }, []Query{
+		{"fails", func(DB) error { return expected }},
+		{"never", func(DB) error {
+			ran = true
+			return nil
+		}},
+	})
+	if err != expected {
+		t.Errorf("expected error %v, got %v", expected, err)
+	}
+	if !cancelled {
+		t.Error("expected the other queries to be cancelled")
+	}
+	if ran {
+		t.Error("expected no query to run after one failed")
+	}
+}
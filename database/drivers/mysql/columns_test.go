@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"bytes"
+	"database/sql"
+	"log"
+	"strings"
+	"testing"
+
+	"gnorm.org/gnorm/database/drivers/mysql/gnorm/columns"
+)
+
+func TestToDBColumnUnsigned(t *testing.T) {
+	tests := []struct {
+		columnType string
+		unsigned   bool
+		zerofill   bool
+	}{
+		{"int(11)", false, false},
+		{"int(10) unsigned", true, false},
+		{"int unsigned", true, false},
+		{"int(10) unsigned zerofill", true, true},
+	}
+	for _, tt := range tests {
+		row := &columns.Row{
+			ColumnName:       "id",
+			DataType:         "int",
+			ColumnType:       tt.columnType,
+			IsNullable:       "NO",
+			NumericPrecision: sql.NullInt64{Int64: 10, Valid: true},
+			NumericScale:     sql.NullInt64{Int64: 0, Valid: true},
+		}
+		col, _, err := toDBColumn(row, log.New(&bytes.Buffer{}, "", 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if col.Unsigned != tt.unsigned || col.Zerofill != tt.zerofill {
+			t.Errorf("%q: expected unsigned %v and zerofill %v, got %v and %v", tt.columnType, tt.unsigned, tt.zerofill, col.Unsigned, col.Zerofill)
+		}
+		if col.ColumnType != tt.columnType {
+			t.Errorf("expected ColumnType %q, got %q", tt.columnType, col.ColumnType)
+		}
+		if col.Precision != 10 {
+			t.Errorf("expected precision 10, got %v", col.Precision)
+		}
+	}
+}
+
+func TestParseEnumValues(t *testing.T) {
+	tests := []struct {
+		columnType string
+		vals       []string
+	}{
+		{"enum('a','b')", []string{"a", "b"}},
+		{"set('read','write','admin')", []string{"read", "write", "admin"}},
+		{"enum('it''s','a,b','')", []string{"it's", "a,b", ""}},
+	}
+	for _, tt := range tests {
+		vals, err := parseEnumValues(tt.columnType)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.columnType, err)
+			continue
+		}
+		if strings.Join(vals, "|") != strings.Join(tt.vals, "|") {
+			t.Errorf("%q: expected %q, got %q", tt.columnType, tt.vals, vals)
+		}
+	}
+
+	for _, bad := range []string{"enum", "enum('a", "enum(a)"} {
+		if _, err := parseEnumValues(bad); err == nil {
+			t.Errorf("%q: expected error but got none", bad)
+		}
+	}
+}
+
+func TestToDBColumnSet(t *testing.T) {
+	row := &columns.Row{
+		ColumnName: "perms",
+		DataType:   "set",
+		ColumnType: "set('read','write','admin')",
+		IsNullable: "NO",
+	}
+	_, enum, err := toDBColumn(row, log.New(&bytes.Buffer{}, "", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enum == nil || !enum.IsSet {
+		t.Fatal("expected set column to produce a set enum")
+	}
+	for x, v := range []int{1, 2, 4} {
+		if enum.Values[x].Value != v {
+			t.Errorf("value %q: expected flag %d, got %d", enum.Values[x].Name, v, enum.Values[x].Value)
+		}
+	}
+}
@@ -1,16 +1,20 @@
 package mysql // import "gnorm.org/gnorm/database/drivers/mysql"
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	// mysql driver
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/pkg/errors"
 
 	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/database/drivers/internal/pool"
+	"gnorm.org/gnorm/database/drivers/mysql/gnorm"
 	"gnorm.org/gnorm/database/drivers/mysql/gnorm/columns"
 	"gnorm.org/gnorm/database/drivers/mysql/gnorm/statistics"
 	"gnorm.org/gnorm/database/drivers/mysql/gnorm/tables"
@@ -19,47 +23,147 @@ import (
 // MySQL implements drivers.Driver interface for MySQL database.
 type MySQL struct{}
 
+func init() {
+	database.Register("mysql", MySQL{})
+}
+
 // Parse reads the mysql schemas for the given schemas and converts them into
 // database.Info structs.
-func (MySQL) Parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*database.Info, error) {
-	return parse(log, conn, schemaNames, filterTables)
+func (MySQL) Parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
+	return parse(ctx, log, conn, opts)
+}
+
+// begin connects to the database and starts the read-only transaction the
+// schema is read in.  The mysql driver doesn't support transaction options,
+// so we start the transaction ourselves on a dedicated connection.
+func begin(ctx context.Context, log *log.Logger, sqldb *sql.DB, opts database.Options) (pool.Tx, error) {
+	conn, err := pool.Conn(ctx, sqldb, opts.ConnectTimeout)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error connecting to mysql")
+	}
+
+	if opts.StatementTimeout > 0 {
+		ms := int64(opts.StatementTimeout / time.Millisecond)
+		if ms < 1 {
+			ms = 1
+		}
+		// max_execution_time only exists from mysql 5.7.8 on.
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", ms)); err != nil {
+			log.Println("statement timeout not supported by this server, ignoring:", err)
+		}
+	}
+
+	start := "START TRANSACTION READ ONLY"
+	if opts.Snapshot {
+		if _, err := conn.ExecContext(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+			conn.Close()
+			return nil, errors.WithMessage(err, "error setting isolation level")
+		}
+		start = "START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY"
+	}
+	if _, err := conn.ExecContext(ctx, start); err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "error starting transaction")
+	}
+	return pool.ConnTx{Conn: conn}, nil
+}
+
+// open starts a transaction on each connection of the pool the queries run
+// on, opts.Parallelism of them.  Unlike postgres, mysql can't share a snapshot
+// between connections, so with opts.Snapshot the schema is read on a single
+// connection, so that every query sees the same consistent snapshot.  The
+// returned function rolls back the transactions and releases the
+// connections.
+func open(ctx context.Context, log *log.Logger, sqldb *sql.DB, opts database.Options) ([]pool.DB, func(), error) {
+	n := opts.Parallelism
+	if opts.Snapshot && n > 1 {
+		log.Printf("reading the schema on 1 connection instead of %d, for a consistent snapshot", n)
+		n = 1
+	}
+	return pool.Open(ctx, log, n, func() (pool.Tx, error) {
+		return begin(ctx, log, sqldb, opts)
+	})
 }
 
-func parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*database.Info, error) {
+func parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
 	log.Println("connecting to mysql with DSN", conn)
-	db, err := sql.Open("mysql", conn)
+	sqldb, err := sql.Open("mysql", conn)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	defer sqldb.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dbs, release, err := open(ctx, log, sqldb, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	schemaNames := opts.Schemas
+
+	var (
+		tableRows   []*tables.Row
+		columnRows  []*columns.Row
+		statRows    []*statistics.Row
+		foreignKeys []*database.ForeignKey
+		views       []*viewResult
+		triggers    []triggerResult
+	)
 	log.Println("querying table schemas for", schemaNames)
-	tables, err := tables.Query(db, tables.TableSchemaCol.In(schemaNames))
+	err = pool.Run(log, dbs, cancel, []pool.Query{
+		{Name: "tables", Run: func(db pool.DB) (err error) {
+			tableRows, err = tables.Query(db, tables.TableSchemaCol.In(schemaNames))
+			return err
+		}},
+		{Name: "columns", Run: func(db pool.DB) (err error) {
+			columnRows, err = columns.Query(db, columns.TableSchemaCol.In(schemaNames))
+			return err
+		}},
+		{Name: "indexes", Run: func(db pool.DB) (err error) {
+			statRows, err = statistics.Query(db, statistics.TableSchemaCol.In(schemaNames))
+			return err
+		}},
+		{Name: "foreign keys", Run: func(db pool.DB) (err error) {
+			foreignKeys, err = queryForeignKeys(log, db, schemaNames)
+			return err
+		}},
+		{Name: "views", Run: func(db pool.DB) (err error) {
+			views, err = queryViews(log, db, schemaNames)
+			return err
+		}},
+		{Name: "triggers", Run: func(db pool.DB) (err error) {
+			triggers, err = queryTriggers(log, db, schemaNames)
+			return err
+		}},
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	schemas := make(map[string][]*database.Table, len(schemaNames))
 
-	for _, t := range tables {
-		if !filterTables(t.TableSchema, t.TableName) {
+	for _, t := range tableRows {
+		if !opts.IncludeTable(t.TableSchema, t.TableName) {
 			continue
 		}
+		kind := database.KindTable
+		if t.TableType == "VIEW" {
+			kind = database.KindView
+		}
 		schemas[t.TableSchema] = append(schemas[t.TableSchema], &database.Table{
 			Name:    t.TableName,
 			Type:    t.TableType,
+			Kind:    kind,
 			Comment: t.TableComment,
 			IsView:  t.TableType == "VIEW",
 		})
 	}
 
-	columns, err := columns.Query(db, columns.TableSchemaCol.In(schemaNames))
-	if err != nil {
-		return nil, err
-	}
-
 	enums := map[string][]*database.Enum{}
 
-	for _, c := range columns {
-		if !filterTables(c.TableSchema, c.TableName) {
+	for _, c := range columnRows {
+		if !opts.IncludeTable(c.TableSchema, c.TableName) {
 			continue
 		}
 		tables, ok := schemas[c.TableSchema]
@@ -88,19 +192,19 @@ func parse(log *log.Logger, conn string, schemaNames []string, filterTables func
 		table.Columns = append(table.Columns, col)
 		if enum != nil {
 			enum.Table = c.TableName
+			enum.Columns = []*database.ColumnRef{{
+				Schema: c.TableSchema,
+				Table:  c.TableName,
+				Column: c.ColumnName,
+			}}
 			enums[c.TableSchema] = append(enums[c.TableSchema], enum)
 		}
 	}
 
 	indexes := make(map[string]map[string][]*database.Index)
 
-	statistics, err := statistics.Query(db, statistics.TableSchemaCol.In(schemaNames))
-	if err != nil {
-		return nil, err
-	}
-
-	for _, s := range statistics {
-		if !filterTables(s.TableSchema, s.TableName) {
+	for _, s := range statRows {
+		if !opts.IncludeTable(s.TableSchema, s.TableName) {
 			continue
 		}
 
@@ -155,12 +259,8 @@ func parse(log *log.Logger, conn string, schemaNames []string, filterTables func
 		index.Columns = append(index.Columns, column)
 	}
 
-	foreignKeys, err := queryForeignKeys(log, db, schemaNames)
-	if err != nil {
-		return nil, err
-	}
 	for _, fk := range foreignKeys {
-		if !filterTables(fk.SchemaName, fk.TableName) {
+		if !opts.IncludeTable(fk.SchemaName, fk.TableName) {
 			log.Printf("skipping constraint %q because it is for filtered-out table %v.%v", fk.Name, fk.SchemaName, fk.TableName)
 			continue
 		}
@@ -192,6 +292,52 @@ func parse(log *log.Logger, conn string, schemaNames []string, filterTables func
 		}
 	}
 
+	for _, r := range views {
+		if !opts.IncludeTable(r.SchemaName, r.TableName) {
+			continue
+		}
+
+		tables, ok := schemas[r.SchemaName]
+		if !ok {
+			log.Printf("Should be impossible: view %q references unknown schema %q", r.TableName, r.SchemaName)
+			continue
+		}
+
+		var table *database.Table
+		for _, t := range tables {
+			if t.Name == r.TableName {
+				table = t
+				break
+			}
+		}
+		if table == nil {
+			log.Printf("Should be impossible: unknown view %q in schema %q", r.TableName, r.SchemaName)
+			continue
+		}
+		table.ViewDefinition = r.Definition
+		table.SourceColumns = r.SourceColumns
+	}
+
+	tablesByName := map[string]*database.Table{}
+	for schema, tables := range schemas {
+		for _, t := range tables {
+			tablesByName[schema+"."+t.Name] = t
+		}
+	}
+	for _, r := range triggers {
+		if !opts.IncludeTable(r.SchemaName, r.TableName) {
+			continue
+		}
+		table, ok := tablesByName[r.SchemaName+"."+r.TableName]
+		if !ok {
+			// the queries may not share a snapshot, so the table may have
+			// been created after the tables were read.
+			log.Printf("skipping trigger %q of unknown table %v.%v", r.Trigger.Name, r.SchemaName, r.TableName)
+			continue
+		}
+		table.Triggers = append(table.Triggers, r.Trigger)
+	}
+
 	res := &database.Info{Schemas: make([]*database.Schema, 0, len(schemas))}
 	for _, schema := range schemaNames {
 		tables := schemas[schema]
@@ -200,13 +346,8 @@ func parse(log *log.Logger, conn string, schemaNames []string, filterTables func
 			Tables: tables,
 			Enums:  enums[schema],
 		}
-
-		dbtables := make(map[string]*database.Table, len(tables))
-		for _, t := range tables {
-			dbtables[t.Name] = t
-		}
 		for tname, index := range indexes[schema] {
-			dbtables[tname].Indexes = index
+			tablesByName[schema+"."+tname].Indexes = index
 		}
 
 		res.Schemas = append(res.Schemas, s)
@@ -217,14 +358,22 @@ func parse(log *log.Logger, conn string, schemaNames []string, filterTables func
 
 func toDBColumn(c *columns.Row, log *log.Logger) (*database.Column, *database.Enum, error) {
 	col := &database.Column{
-		Name:         c.ColumnName,
-		Nullable:     c.IsNullable == "YES",
-		HasDefault:   c.ColumnDefault.String != "",
-		Type:         c.DataType,
-		Comment:      c.ColumnComment,
-		Ordinal:      c.OrdinalPosition,
-		Orig:         *c,
-		IsPrimaryKey: strings.Contains(c.ColumnKey, "PRI"),
+		Name:              c.ColumnName,
+		Nullable:          c.IsNullable == "YES",
+		HasDefault:        c.ColumnDefault.String != "",
+		Type:              c.DataType,
+		Precision:         int(c.NumericPrecision.Int64),
+		Scale:             int(c.NumericScale.Int64),
+		DatetimePrecision: int(c.DatetimePrecision.Int64),
+		CharacterSet:      c.CharacterSetName.String,
+		Collation:         c.CollationName.String,
+		ColumnType:        c.ColumnType,
+		Unsigned:          strings.Contains(c.ColumnType, " unsigned"),
+		Zerofill:          strings.Contains(c.ColumnType, " zerofill"),
+		Comment:           c.ColumnComment,
+		Ordinal:           c.OrdinalPosition,
+		Orig:              *c,
+		IsPrimaryKey:      strings.Contains(c.ColumnKey, "PRI"),
 	}
 
 	// MySQL always specifies length even if it's not a part of the type. We
@@ -234,41 +383,83 @@ func toDBColumn(c *columns.Row, log *log.Logger) (*database.Column, *database.En
 		col.Length = int(c.CharacterMaximumLength.Int64)
 	}
 
-	if col.Type != "enum" {
+	if col.Type != "enum" && col.Type != "set" {
 		return col, nil, nil
 	}
-	// in mysql, enums are specific to a column in a table, so all their data is
-	// contained in the column they're used by.
+	// in mysql, enums and sets are specific to a column in a table, so all
+	// their data is contained in the column they're used by.
 
-	// column type should be enum('foo', 'bar')
-	if len(c.ColumnType) < 5 {
-		return nil, nil, errors.New("unexpected column type: " + c.ColumnType)
+	// column type should be enum('foo','bar') or set('foo','bar')
+	vals, err := parseEnumValues(c.ColumnType)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// we'll call the enum the same as the column name.
+	// we'll call the enum the same as the column name, it can be renamed
+	// with the MySQLEnumNaming template.
 	// the function above will set the table name etc
 	enum := &database.Enum{
-		Name: col.Name,
+		Name:  col.Name,
+		IsSet: col.Type == "set",
 	}
-	// strip off the enum and parens
-	s := c.ColumnType[5 : len(c.ColumnType)-1]
-	vals := strings.Split(s, ",")
 	enum.Values = make([]*database.EnumValue, len(vals))
 	for x := range vals {
 		enum.Values[x] = &database.EnumValue{
-			// strip off the quotes
-			Name: vals[x][1 : len(vals[x])-1],
+			Name: vals[x],
 			// enum values start at 1 in mysql
 			Value: x + 1,
 		}
+		if enum.IsSet {
+			// set values are bit flags
+			enum.Values[x].Value = 1 << uint(x)
+		}
 	}
 
 	return col, enum, nil
 }
 
-func queryForeignKeys(log *log.Logger, db *sql.DB, schemas []string) ([]*database.ForeignKey, error) {
+// parseEnumValues parses the list of values out of a mysql column type like
+// enum('foo','bar') or set('foo','bar').  Single quotes in values are escaped
+// by doubling them.
+func parseEnumValues(columnType string) ([]string, error) {
+	open := strings.Index(columnType, "(")
+	if open == -1 || !strings.HasSuffix(columnType, ")") {
+		return nil, errors.New("unexpected column type: " + columnType)
+	}
+	s := columnType[open+1 : len(columnType)-1]
+
+	var vals []string
+	for len(s) > 0 {
+		if s[0] != '\'' {
+			return nil, errors.New("unexpected column type: " + columnType)
+		}
+		var val []byte
+		x := 1
+		for {
+			if x >= len(s) {
+				return nil, errors.New("unterminated value in column type: " + columnType)
+			}
+			if s[x] == '\'' {
+				if x+1 < len(s) && s[x+1] == '\'' {
+					val = append(val, '\'')
+					x += 2
+					continue
+				}
+				break
+			}
+			val = append(val, s[x])
+			x++
+		}
+		vals = append(vals, string(val))
+		// skip the closing quote and the comma after it.
+		s = strings.TrimPrefix(s[x+1:], ",")
+	}
+	return vals, nil
+}
+
+func queryForeignKeys(log *log.Logger, db gnorm.DB, schemas []string) ([]*database.ForeignKey, error) {
 	// TODO: make this work with Gnorm generated types
-	const q = `SELECT lkc.TABLE_SCHEMA, lkc.TABLE_NAME, lkc.COLUMN_NAME, lkc.CONSTRAINT_NAME, lkc.POSITION_IN_UNIQUE_CONSTRAINT, lkc.REFERENCED_TABLE_NAME, lkc.REFERENCED_COLUMN_NAME
+	const q = `SELECT lkc.TABLE_SCHEMA, lkc.TABLE_NAME, lkc.COLUMN_NAME, lkc.CONSTRAINT_NAME, lkc.POSITION_IN_UNIQUE_CONSTRAINT, lkc.REFERENCED_TABLE_SCHEMA, lkc.REFERENCED_TABLE_NAME, lkc.REFERENCED_COLUMN_NAME
 	  FROM information_schema.REFERENTIAL_CONSTRAINTS as rc
   		LEFT JOIN information_schema.KEY_COLUMN_USAGE as lkc
           ON lkc.CONSTRAINT_SCHEMA = rc.CONSTRAINT_SCHEMA
@@ -290,7 +481,7 @@ func queryForeignKeys(log *log.Logger, db *sql.DB, schemas []string) ([]*databas
 
 	for rows.Next() {
 		fk := &database.ForeignKey{}
-		if err := rows.Scan(&fk.SchemaName, &fk.TableName, &fk.ColumnName, &fk.Name, &fk.UniqueConstraintPosition, &fk.ForeignTableName, &fk.ForeignColumnName); err != nil {
+		if err := rows.Scan(&fk.SchemaName, &fk.TableName, &fk.ColumnName, &fk.Name, &fk.UniqueConstraintPosition, &fk.ForeignSchemaName, &fk.ForeignTableName, &fk.ForeignColumnName); err != nil {
 			return nil, errors.WithMessage(err, "error scanning foreign key constraint")
 		}
 		ret = append(ret, fk)
@@ -301,3 +492,123 @@ func queryForeignKeys(log *log.Logger, db *sql.DB, schemas []string) ([]*databas
 
 	return ret, nil
 }
+
+type triggerResult struct {
+	SchemaName string
+	TableName  string
+	Trigger    *database.Trigger
+}
+
+func queryTriggers(log *log.Logger, db gnorm.DB, schemas []string) ([]triggerResult, error) {
+	const q = `SELECT EVENT_OBJECT_SCHEMA, EVENT_OBJECT_TABLE, TRIGGER_NAME, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORIENTATION, ACTION_STATEMENT
+	  FROM information_schema.TRIGGERS
+	  WHERE EVENT_OBJECT_SCHEMA IN (%s)
+	  ORDER BY EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_ORDER`
+	spots := make([]string, len(schemas))
+	vals := make([]interface{}, len(schemas))
+	for x := range schemas {
+		spots[x] = "?"
+		vals[x] = schemas[x]
+	}
+	query := fmt.Sprintf(q, strings.Join(spots, ", "))
+	rows, err := db.Query(query, vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying triggers")
+	}
+	defer rows.Close()
+	var ret []triggerResult
+
+	for rows.Next() {
+		var r triggerResult
+		var event, orientation string
+		t := &database.Trigger{}
+		if err := rows.Scan(&r.SchemaName, &r.TableName, &t.Name, &t.Timing, &event, &orientation, &t.Definition); err != nil {
+			return nil, errors.WithMessage(err, "error scanning trigger")
+		}
+		// mysql triggers only ever fire for a single event, and always for
+		// each row.
+		t.Events = []string{event}
+		t.ForEachRow = orientation == "ROW"
+		r.Trigger = t
+		ret = append(ret, r)
+	}
+	if rows.Err() != nil {
+		return nil, errors.WithMessage(rows.Err(), "error reading triggers")
+	}
+	log.Printf("found %d triggers for all tables in all schemas", len(ret))
+
+	return ret, nil
+}
+
+type viewResult struct {
+	SchemaName    string
+	TableName     string
+	Definition    string
+	SourceColumns []*database.ColumnRef
+}
+
+func queryViews(log *log.Logger, db gnorm.DB, schemas []string) ([]*viewResult, error) {
+	const q = `SELECT TABLE_SCHEMA, TABLE_NAME, VIEW_DEFINITION
+	  FROM information_schema.VIEWS
+	  WHERE TABLE_SCHEMA IN (%s)`
+	spots := make([]string, len(schemas))
+	vals := make([]interface{}, len(schemas))
+	for x := range schemas {
+		spots[x] = "?"
+		vals[x] = schemas[x]
+	}
+	query := fmt.Sprintf(q, strings.Join(spots, ", "))
+	rows, err := db.Query(query, vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying views")
+	}
+	defer rows.Close()
+	var ret []*viewResult
+	byName := map[string]*viewResult{}
+
+	for rows.Next() {
+		r := &viewResult{}
+		if err := rows.Scan(&r.SchemaName, &r.TableName, &r.Definition); err != nil {
+			return nil, errors.WithMessage(err, "error scanning view")
+		}
+		ret = append(ret, r)
+		byName[r.SchemaName+"."+r.TableName] = r
+	}
+	if rows.Err() != nil {
+		return nil, errors.WithMessage(rows.Err(), "error reading views")
+	}
+	log.Printf("found %d views for all schemas", len(ret))
+
+	// VIEW_COLUMN_USAGE was added in mysql 8.0.13, so older servers just
+	// don't get view lineage.
+	const uq = `SELECT VIEW_SCHEMA, VIEW_NAME, TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME
+	  FROM information_schema.VIEW_COLUMN_USAGE
+	  WHERE VIEW_SCHEMA IN (%s)
+	  ORDER BY VIEW_SCHEMA, VIEW_NAME, TABLE_SCHEMA, TABLE_NAME, COLUMN_NAME`
+	query = fmt.Sprintf(uq, strings.Join(spots, ", "))
+	urows, err := db.Query(query, vals...)
+	if err != nil {
+		log.Println("not reading view column usage:", err)
+		return ret, nil
+	}
+	defer urows.Close()
+
+	for urows.Next() {
+		var schema, view string
+		src := &database.ColumnRef{}
+		if err := urows.Scan(&schema, &view, &src.Schema, &src.Table, &src.Column); err != nil {
+			return nil, errors.WithMessage(err, "error scanning view column usage")
+		}
+		r, ok := byName[schema+"."+view]
+		if !ok {
+			log.Printf("Should be impossible: column usage references unknown view %q in schema %q", view, schema)
+			continue
+		}
+		r.SourceColumns = append(r.SourceColumns, src)
+	}
+	if urows.Err() != nil {
+		return nil, errors.WithMessage(urows.Err(), "error reading view column usage")
+	}
+
+	return ret, nil
+}
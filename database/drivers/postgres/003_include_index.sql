@@ -0,0 +1,12 @@
+-- Write your migrate up statements here
+
+-- pages is a non-key column of the index, so the index's only column is
+-- author_id.
+CREATE INDEX books_author_pages_idx ON books(author_id) INCLUDE (pages);
+
+---- create above / drop below ----
+
+DROP INDEX IF EXISTS books_author_pages_idx;
+
+-- Write your migrate down statements here. If this migration is irreversible
+-- Then delete the separator line above.
@@ -5,6 +5,7 @@ import (
 	"log"
 	"testing"
 
+	"gnorm.org/gnorm/database"
 	"gnorm.org/gnorm/database/drivers/postgres/gnorm/columns"
 )
 
@@ -138,6 +139,47 @@ var (
 		IdentityCycle:   sql.NullString{String: "NO", Valid: true}, IsGenerated: sql.NullString{String: "NEVER", Valid: true},
 		IsUpdatable: sql.NullString{String: "YES", Valid: true},
 	}
+
+	// numeric(12,2)
+	PriceCol = &columns.Row{
+		TableCatalog:          sql.NullString{String: "gnorm-db", Valid: true},
+		TableSchema:           sql.NullString{String: "public", Valid: true},
+		TableName:             sql.NullString{String: "books", Valid: true},
+		ColumnName:            sql.NullString{String: "price", Valid: true},
+		OrdinalPosition:       sql.NullInt64{Int64: 9, Valid: true},
+		IsNullable:            sql.NullString{String: "NO", Valid: true},
+		DataType:              sql.NullString{String: "numeric", Valid: true},
+		NumericPrecision:      sql.NullInt64{Int64: 12, Valid: true},
+		NumericPrecisionRadix: sql.NullInt64{Int64: 10, Valid: true},
+		NumericScale:          sql.NullInt64{Int64: 2, Valid: true},
+		UdtCatalog:            sql.NullString{String: "gnorm-db", Valid: true},
+		UdtSchema:             sql.NullString{String: "pg_catalog", Valid: true},
+		UdtName:               sql.NullString{String: "numeric", Valid: true},
+		DtdIdentifier:         sql.NullString{String: "9", Valid: true},
+		IsGenerated:           sql.NullString{String: "NEVER", Valid: true},
+		IsUpdatable:           sql.NullString{String: "YES", Valid: true},
+	}
+
+	// text column with a domain type
+	EmailCol = &columns.Row{
+		TableCatalog:         sql.NullString{String: "gnorm-db", Valid: true},
+		TableSchema:          sql.NullString{String: "public", Valid: true},
+		TableName:            sql.NullString{String: "authors", Valid: true},
+		ColumnName:           sql.NullString{String: "email", Valid: true},
+		OrdinalPosition:      sql.NullInt64{Int64: 3, Valid: true},
+		IsNullable:           sql.NullString{String: "YES", Valid: true},
+		DataType:             sql.NullString{String: "text", Valid: true},
+		CharacterOctetLength: sql.NullInt64{Int64: 1073741824, Valid: true},
+		DomainCatalog:        sql.NullString{String: "gnorm-db", Valid: true},
+		DomainSchema:         sql.NullString{String: "public", Valid: true},
+		DomainName:           sql.NullString{String: "email", Valid: true},
+		UdtCatalog:           sql.NullString{String: "gnorm-db", Valid: true},
+		UdtSchema:            sql.NullString{String: "pg_catalog", Valid: true},
+		UdtName:              sql.NullString{String: "text", Valid: true},
+		DtdIdentifier:        sql.NullString{String: "3", Valid: true},
+		IsGenerated:          sql.NullString{String: "NEVER", Valid: true},
+		IsUpdatable:          sql.NullString{String: "YES", Valid: true},
+	}
 )
 
 type testLog struct {
@@ -259,3 +301,126 @@ func TestDBType(t *testing.T) {
 		t.Errorf("Expected column to have UdtName %q as Type, but instead got %s", BookTypeCol.UdtName.String, col.Type)
 	}
 }
+
+func TestDomain(t *testing.T) {
+	col := toDBColumn(SummaryCol, tLog(t))
+	if col.Domain != "" {
+		t.Errorf("Text column should not have a domain, but has %q.", col.Domain)
+	}
+
+	col = toDBColumn(EmailCol, tLog(t))
+	if col.Domain != "email" {
+		t.Errorf("Expected column to have domain %q, but got %q", "email", col.Domain)
+	}
+	if col.Type != "email" {
+		t.Errorf("Expected column with a domain to have the domain name %q as Type, but got %q", "email", col.Type)
+	}
+	if !col.UserDefined {
+		t.Error("column with a domain type not marked as UserDefined")
+	}
+}
+
+func TestPGType(t *testing.T) {
+	tests := []struct {
+		dataType, udtName string
+		typ               string
+		isArray           bool
+		userDefined       bool
+	}{
+		{"text", "text", "text", false, false},
+		{"ARRAY", "_int4", "int4", true, false},
+		{"USER-DEFINED", "book_type", "book_type", false, true},
+	}
+	for _, tt := range tests {
+		typ, isArray, userDefined := pgType(tt.dataType, tt.udtName)
+		if typ != tt.typ || isArray != tt.isArray || userDefined != tt.userDefined {
+			t.Errorf("pgType(%q, %q) = (%q, %v, %v), expected (%q, %v, %v)", tt.dataType, tt.udtName, typ, isArray, userDefined, tt.typ, tt.isArray, tt.userDefined)
+		}
+	}
+}
+
+func TestColumnType(t *testing.T) {
+	tests := []struct {
+		row        *columns.Row
+		columnType string
+	}{
+		{PriceCol, "numeric(12,2)"},
+		{ISBNCol, "character(32)"},
+		{BooksIDCol, ""},
+		{SummaryCol, ""},
+		{YearsCol, ""},
+		{EmailCol, ""},
+	}
+	for _, tt := range tests {
+		col := toDBColumn(tt.row, tLog(t))
+		if col.ColumnType != tt.columnType {
+			t.Errorf("column %q: expected ColumnType %q, got %q", col.Name, tt.columnType, col.ColumnType)
+		}
+	}
+
+	col := toDBColumn(PriceCol, tLog(t))
+	if col.Precision != 12 || col.Scale != 2 {
+		t.Errorf("expected precision 12 and scale 2, got %v and %v", col.Precision, col.Scale)
+	}
+}
+
+func TestTypeSchema(t *testing.T) {
+	tests := []struct {
+		row        *columns.Row
+		typeSchema string
+	}{
+		{BookTypeCol, "public"},
+		{EmailCol, "public"},
+		{SummaryCol, ""},
+		{YearsCol, ""},
+	}
+	for _, tt := range tests {
+		col := toDBColumn(tt.row, tLog(t))
+		if col.TypeSchema != tt.typeSchema {
+			t.Errorf("column %q: expected TypeSchema %q, got %q", col.Name, tt.typeSchema, col.TypeSchema)
+		}
+	}
+}
+
+func TestPostGISTypmod(t *testing.T) {
+	tests := []struct {
+		formatted    string
+		geometryType string
+		srid         int
+	}{
+		{"geometry(Point,4326)", "Point", 4326},
+		{"geography(MultiPolygonZ)", "MultiPolygonZ", 0},
+		{"geometry(LineString,3857)[]", "LineString", 3857},
+		{"geometry", "", 0},
+		{"integer", "", 0},
+	}
+	for _, tt := range tests {
+		typ, srid := postGISTypmod(tt.formatted)
+		if typ != tt.geometryType || srid != tt.srid {
+			t.Errorf("postGISTypmod(%q) = (%q, %d), expected (%q, %d)", tt.formatted, typ, srid, tt.geometryType, tt.srid)
+		}
+	}
+}
+
+func TestColumnDetails(t *testing.T) {
+	col := &database.Column{IsArray: true}
+	columnDetails{Dimensions: 0, FormattedType: "int4range[]", RangeSubtype: "integer"}.apply(col)
+	if col.ArrayDimensions != 1 {
+		t.Errorf("expected undeclared array dimensions to be 1, got %d", col.ArrayDimensions)
+	}
+	if !col.IsRange || col.IsMultirange || col.RangeSubtype != "integer" {
+		t.Errorf("expected integer range, got IsRange %v, IsMultirange %v, RangeSubtype %q", col.IsRange, col.IsMultirange, col.RangeSubtype)
+	}
+
+	col = &database.Column{}
+	columnDetails{FormattedType: "datemultirange", MultirangeSubtype: "date"}.apply(col)
+	if col.ArrayDimensions != 0 || col.IsRange || !col.IsMultirange || col.RangeSubtype != "date" {
+		t.Errorf("expected date multirange, got %#v", col)
+	}
+
+	col = &database.Column{}
+	columnDetails{FormattedType: "geometry(Point,4326)", Extension: "postgis"}.apply(col)
+	if col.Extension != "postgis" || col.GeometryType != "Point" || col.SRID != 4326 {
+		t.Errorf("expected postgis Point with SRID 4326, got %q %q %d", col.Extension, col.GeometryType, col.SRID)
+	}
+}
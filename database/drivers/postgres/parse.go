@@ -1,473 +1,830 @@
 package postgres // import "gnorm.org/gnorm/database/drivers/postgres"
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
-	// register postgres driver
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 	"github.com/pkg/errors"
 
 	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/database/drivers/internal/pool"
+	"gnorm.org/gnorm/database/drivers/postgres/gnorm"
 	"gnorm.org/gnorm/database/drivers/postgres/gnorm/columns"
-	"gnorm.org/gnorm/database/drivers/postgres/gnorm/tables"
 )
 
 // PG implements drivers.Driver interface for interacting with postgresql
 // database.
 type PG struct{}
 
+func init() {
+	database.Register("postgres", PG{})
+}
+
 // Parse reads the postgres schemas for the given schemas and converts them into
 // database.Info structs.
-func (PG) Parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*database.Info, error) {
-	return parse(log, conn, schemaNames, filterTables)
+func (PG) Parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
+	return parse(ctx, log, conn, opts)
+}
+
+// oid is a postgres object identifier.  Everything that belongs to a table is
+// matched up with it by the table's oid, since table names are only unique
+// within a schema.
+type oid uint32
+
+// relation is a table we're generating data for, with its columns indexed
+// for the queries that reference them.
+type relation struct {
+	table        *database.Table
+	columnsByNum map[int64]*database.Column
+	columnByName map[string]*database.Column
+}
+
+// connTx is a transaction on a dedicated connection, which is released when
+// the transaction is rolled back.
+type connTx struct {
+	*sql.Tx
+	conn *sql.Conn
+}
+
+func (t connTx) Rollback() error {
+	err := t.Tx.Rollback()
+	if cerr := t.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// begin connects to the database within the connect timeout and starts a
+// read-only transaction the schema is read in.  The transaction lives as long
+// as ctx, so the timeout can't be part of it.  If snapshot is not empty, the
+// transaction imports that snapshot, so it sees exactly what the transaction
+// that exported it sees.
+func begin(ctx context.Context, sqldb *sql.DB, opts database.Options, snapshot string) (pool.Tx, error) {
+	conn, err := pool.Conn(ctx, sqldb, opts.ConnectTimeout)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error connecting to postgres")
+	}
+	txOpts := &sql.TxOptions{ReadOnly: true}
+	if opts.Snapshot {
+		txOpts.Isolation = sql.LevelRepeatableRead
+	}
+	sqltx, err := conn.BeginTx(ctx, txOpts)
+	if err != nil {
+		conn.Close()
+		return nil, errors.WithMessage(err, "error starting transaction")
+	}
+	tx := connTx{Tx: sqltx, conn: conn}
+	if snapshot != "" {
+		// this has to come before any other statement in the transaction, and
+		// can't take a query parameter.
+		lit := "'" + strings.Replace(snapshot, "'", "''", -1) + "'"
+		if _, err := tx.ExecContext(ctx, "SET TRANSACTION SNAPSHOT "+lit); err != nil {
+			tx.Rollback()
+			return nil, errors.WithMessage(err, "error importing snapshot")
+		}
+	}
+	if opts.StatementTimeout > 0 {
+		ms := int64(opts.StatementTimeout / time.Millisecond)
+		if ms < 1 {
+			ms = 1
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", ms)); err != nil {
+			tx.Rollback()
+			return nil, errors.WithMessage(err, "error setting statement timeout")
+		}
+	}
+	return tx, nil
 }
 
-func parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*database.Info, error) {
+// open connects to the database and starts a transaction on each connection
+// of the pool the queries run on, opts.Parallelism of them.  With
+// opts.Snapshot, the first transaction exports its snapshot and the others
+// import it, the way pg_dump --jobs does, so all the queries see the same
+// state of the catalog.  The returned function rolls back the transactions.
+func open(ctx context.Context, log *log.Logger, sqldb *sql.DB, opts database.Options) ([]pool.DB, func(), error) {
+	n := opts.Parallelism
+	if n < 1 {
+		n = 1
+	}
+	sqldb.SetMaxOpenConns(n)
+	sqldb.SetMaxIdleConns(n)
+
+	var snapshot string
+	return pool.Open(ctx, log, n, func() (pool.Tx, error) {
+		tx, err := begin(ctx, sqldb, opts, snapshot)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Snapshot && n > 1 && snapshot == "" {
+			if err := tx.QueryRowContext(ctx, "SELECT pg_catalog.pg_export_snapshot()").Scan(&snapshot); err != nil {
+				tx.Rollback()
+				return nil, errors.WithMessage(err, "error exporting snapshot")
+			}
+		}
+		return tx, nil
+	})
+}
+
+func parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
 	log.Println("connecting to postgres with DSN", conn)
-	db, err := sql.Open("postgres", conn)
+	sqldb, err := sql.Open("postgres", conn)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
-	sch := make([]sql.NullString, len(schemaNames))
-	for x := range schemaNames {
-		sch[x] = sql.NullString{String: schemaNames[x], Valid: true}
-	}
+	defer sqldb.Close()
 
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	dbs, rollback, err := open(ctx, log, sqldb, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer rollback()
+	schemaNames := opts.Schemas
+
+	var (
+		tables         []tableResult
+		columnResults  []columnResult
+		primaryKeys    []primaryKeyResult
+		foreignKeys    []foreignKeyResult
+		enums          map[string][]*database.Enum
+		indexResults   []indexResult
+		viewColumns    []viewColumnResult
+		domains        map[string][]*database.Domain
+		composites     map[string][]*database.CompositeType
+		sequences      map[string][]*database.Sequence
+		triggerResults []triggerResult
+	)
 	log.Println("querying table schemas for", schemaNames)
-	tables, err := tables.Query(db, tables.TableSchemaCol.In(sch))
+	err = pool.Run(log, dbs, cancel, []pool.Query{
+		{Name: "tables", Run: func(db pool.DB) (err error) {
+			tables, err = queryTables(log, db, schemaNames)
+			return err
+		}},
+		{Name: "columns", Run: func(db pool.DB) (err error) {
+			columnResults, err = queryColumns(log, db, schemaNames)
+			return err
+		}},
+		{Name: "primary keys", Run: func(db pool.DB) (err error) {
+			primaryKeys, err = queryPrimaryKeys(log, db, schemaNames)
+			return err
+		}},
+		{Name: "foreign keys", Run: func(db pool.DB) (err error) {
+			foreignKeys, err = queryForeignKeys(log, db, schemaNames)
+			return err
+		}},
+		{Name: "enums", Run: func(db pool.DB) (err error) {
+			enums, err = queryEnums(log, db, schemaNames)
+			return err
+		}},
+		{Name: "indexes", Run: func(db pool.DB) (err error) {
+			indexResults, err = queryIndexes(log, db, schemaNames)
+			return err
+		}},
+		{Name: "view columns", Run: func(db pool.DB) (err error) {
+			viewColumns, err = queryViewColumnUsage(log, db, schemaNames)
+			return err
+		}},
+		{Name: "domains", Run: func(db pool.DB) (err error) {
+			domains, err = queryDomains(log, db, schemaNames)
+			return err
+		}},
+		{Name: "composite types", Run: func(db pool.DB) (err error) {
+			composites, err = queryCompositeTypes(log, db, schemaNames)
+			return err
+		}},
+		{Name: "sequences", Run: func(db pool.DB) (err error) {
+			sequences, err = querySequences(log, db, schemaNames)
+			return err
+		}},
+		{Name: "triggers", Run: func(db pool.DB) (err error) {
+			triggerResults, err = queryTriggers(log, db, schemaNames)
+			return err
+		}},
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	log.Printf("found %v tables", len(tables))
 	schemas := make(map[string][]*database.Table, len(schemaNames))
+	relations := make(map[oid]*relation, len(tables))
 	for _, t := range tables {
-		if !filterTables(t.TableSchema.String, t.TableName.String) {
-			log.Printf("skipping filtered-out table %v.%v", t.TableSchema.String, t.TableName.String)
+		if !opts.IncludeTable(t.SchemaName, t.Table.Name) {
+			log.Printf("skipping filtered-out table %v.%v", t.SchemaName, t.Table.Name)
 			continue
 		}
 
-		schemas[t.TableSchema.String] = append(schemas[t.TableSchema.String], &database.Table{
-			Name:         t.TableName.String,
-			Type:         t.TableType.String,
-			IsView:       t.TableType.String == "VIEW",
-			IsInsertable: t.IsInsertableInto.String == "YES",
-		})
+		schemas[t.SchemaName] = append(schemas[t.SchemaName], t.Table)
+		relations[t.OID] = &relation{
+			table:        t.Table,
+			columnsByNum: map[int64]*database.Column{},
+			columnByName: map[string]*database.Column{},
+		}
 	}
 
-	columns, err := columns.Query(db, columns.TableSchemaCol.In(sch))
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("found %v columns for all tables in all specified schemas", len(columns))
-	for _, c := range columns {
-		if !filterTables(c.TableSchema.String, c.TableName.String) {
-			log.Printf("skipping column %q because it is for filtered-out table %v.%v", c.ColumnName.String, c.TableSchema.String, c.TableName.String)
-			continue
-		}
+	// Everything below is read for all tables in the schemas at once, so rows
+	// for tables that aren't in relations belong to filtered-out tables and
+	// are skipped.
 
-		tables, ok := schemas[c.TableSchema.String]
+	log.Printf("found %v columns for all tables in all specified schemas", len(columnResults))
+	for _, r := range columnResults {
+		rel, ok := relations[r.RelID]
 		if !ok {
-			log.Printf("Should be impossible: column %q references unknown schema %q", c.ColumnName.String, c.TableSchema.String)
-			continue
-		}
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == c.TableName.String {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: column %q references unknown table %q in schema %q", c.ColumnName.String, c.TableName.String, c.TableSchema.String)
 			continue
 		}
-
-		col := toDBColumn(c, log)
-		table.Columns = append(table.Columns, col)
+		col := toDBColumn(r.Row, log)
+		r.Details.apply(col)
+		col.Comment = r.Comment
+		rel.table.Columns = append(rel.table.Columns, col)
+		rel.columnsByNum[col.Ordinal] = col
+		rel.columnByName[col.Name] = col
 	}
 
-	primaryKeys, err := queryPrimaryKeys(log, db, schemaNames)
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("found %v primary keys", len(primaryKeys))
+	log.Printf("found %v primary key columns", len(primaryKeys))
 	for _, pk := range primaryKeys {
-		if !filterTables(pk.SchemaName, pk.TableName) {
-			log.Printf("skipping constraint %q because it is for filtered-out table %v.%v", pk.Name, pk.SchemaName, pk.TableName)
-			continue
-		}
-
-		tables, ok := schemas[pk.SchemaName]
+		rel, ok := relations[pk.RelID]
 		if !ok {
-			log.Printf("Should be impossible: constraint %q references unknown schema %q", pk.Name, pk.SchemaName)
 			continue
 		}
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == pk.TableName {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: constraint %q references unknown table %q in schema %q", pk.Name, pk.TableName, pk.SchemaName)
+		col, ok := rel.columnsByNum[pk.AttNum]
+		if !ok {
+			log.Printf("Should be impossible: constraint %q references unknown column %d of table %q", pk.Name, pk.AttNum, rel.table.Name)
 			continue
 		}
-
-		for _, col := range table.Columns {
-			if pk.ColumnName != col.Name {
-				continue
-			}
-			col.IsPrimaryKey = true
-		}
+		col.IsPrimaryKey = true
 	}
 
-	foreignKeys, err := queryForeignKeys(log, db, schemaNames)
-	if err != nil {
-		return nil, err
-	}
-	for _, fk := range foreignKeys {
-		if !filterTables(fk.SchemaName, fk.TableName) {
-			log.Printf("skipping constraint %q because it is for filtered-out table %v.%v", fk.Name, fk.SchemaName, fk.TableName)
-			continue
-		}
-
-		tables, ok := schemas[fk.SchemaName]
+	log.Printf("found %v foreign key columns", len(foreignKeys))
+	for _, r := range foreignKeys {
+		rel, ok := relations[r.RelID]
 		if !ok {
-			log.Printf("Should be impossible: constraint %q references unknown schema %q", fk.Name, fk.SchemaName)
 			continue
 		}
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == fk.TableName {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: constraint %q references unknown table %q in schema %q", fk.Name, fk.TableName, fk.SchemaName)
+		col, ok := rel.columnsByNum[r.AttNum]
+		if !ok {
+			log.Printf("Should be impossible: constraint %q references unknown column %q of table %q", r.ForeignKey.Name, r.ForeignKey.ColumnName, rel.table.Name)
 			continue
 		}
-
-		for _, col := range table.Columns {
-			if fk.ColumnName != col.Name {
-				continue
-			}
-			col.IsForeignKey = true
-			col.ForeignKey = fk
-		}
+		col.IsForeignKey = true
+		col.ForeignKey = r.ForeignKey
 	}
 
-	enums, err := queryEnums(log, db, schemaNames)
-	if err != nil {
-		return nil, err
-	}
-	log.Printf("found %v enums for all schemas", len(enums))
-
-	indexResults, err := queryIndexes(log, db, schemaNames)
-	if err != nil {
-		return nil, err
-	}
 	log.Printf("found %d indexes for all tables in all schemas", len(indexResults))
 
-	indexes := make(map[string]map[string][]*database.Index)
 outer:
 	for _, r := range indexResults {
-		if !filterTables(r.SchemaName, r.TableName) {
-			continue
-		}
-
-		tables, ok := schemas[r.SchemaName]
+		rel, ok := relations[r.RelID]
 		if !ok {
-			log.Printf("Should be impossible: index %q references unknown schema %q", r.IndexName, r.SchemaName)
-			continue
-		}
-
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == r.TableName {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: index %q references unknown table %q", r.IndexName, r.TableName)
 			continue
 		}
 
-		columnMap := make(map[string]*database.Column, len(table.Columns))
-		for _, c := range table.Columns {
-			columnMap[c.Name] = c
-		}
-
-		columns := make([]*database.Column, 0)
+		columns := make([]*database.Column, 0, len(r.Columns))
 		for _, c := range r.Columns {
-			column, cok := columnMap[c]
-			if !cok {
+			column, ok := rel.columnByName[c]
+			if !ok {
 				log.Printf("Should be impossible: index %q references unknown column %q", r.IndexName, c)
 				continue outer
 			}
 			columns = append(columns, column)
 		}
+		rel.table.Indexes = append(rel.table.Indexes, &database.Index{
+			Name:     r.IndexName,
+			IsUnique: r.IsUnique,
+			Columns:  columns,
+			Comment:  r.Comment,
+		})
+	}
 
-		schemaIndex, ok := indexes[r.SchemaName]
+	log.Printf("found %d source columns for all views in all schemas", len(viewColumns))
+	for _, r := range viewColumns {
+		rel, ok := relations[r.RelID]
 		if !ok {
-			schemaIndex = make(map[string][]*database.Index)
-			indexes[r.SchemaName] = schemaIndex
+			continue
 		}
+		rel.table.SourceColumns = append(rel.table.SourceColumns, r.Source)
+	}
 
-		var index *database.Index
-		for _, i := range schemaIndex[r.TableName] {
-			if i.Name == r.IndexName {
-				index = i
-				break
-			}
-		}
-		if index == nil {
-			index = &database.Index{Name: r.IndexName, IsUnique: r.IsUnique}
-			schemaIndex[r.TableName] = append(schemaIndex[r.TableName], index)
+	log.Printf("found %d triggers for all tables in all schemas", len(triggerResults))
+	for _, r := range triggerResults {
+		rel, ok := relations[r.RelID]
+		if !ok {
+			continue
 		}
+		rel.table.Triggers = append(rel.table.Triggers, r.Trigger)
+	}
 
-		index.Columns = columns
+	res := &database.Info{Schemas: make([]*database.Schema, 0, len(schemas))}
+	for _, schema := range schemaNames {
+		res.Schemas = append(res.Schemas, &database.Schema{
+			Name:           schema,
+			Tables:         schemas[schema],
+			Enums:          enums[schema],
+			CompositeTypes: composites[schema],
+			Domains:        domains[schema],
+			Sequences:      sequences[schema],
+		})
 	}
 
-	columnCommentResults, err := queryColumnComments(log, db, schemaNames)
-	if err != nil {
-		return nil, err
+	return res, nil
+}
+
+// schemaParams returns the placeholders and values for a query that filters
+// on the given schema names.
+func schemaParams(schemas []string) (string, []interface{}) {
+	spots := make([]string, len(schemas))
+	vals := make([]interface{}, len(schemas))
+	for x := range schemas {
+		spots[x] = fmt.Sprintf("$%v", x+1)
+		vals[x] = schemas[x]
 	}
-	log.Printf("found %d comments for all columns in all tables in all specified schemas", len(columnCommentResults))
+	return strings.Join(spots, ", "), vals
+}
 
-	for _, r := range columnCommentResults {
-		if !filterTables(r.SchemaName, r.TableName) {
-			continue
-		}
+type tableResult struct {
+	OID        oid
+	SchemaName string
+	Table      *database.Table
+}
 
-		tables, ok := schemas[r.SchemaName]
-		if !ok {
-			log.Printf("Should be impossible: comment for %q.%q.%q references unknown schema %q",
-				r.SchemaName, r.TableName, r.ColumnName, r.SchemaName)
-			continue
-		}
+func queryTables(log *log.Logger, db gnorm.DB, schemas []string) ([]tableResult, error) {
+	// we read pg_class directly rather than information_schema.tables, since
+	// the latter doesn't include materialized views and can't tell us about
+	// partitions or inheritance.
+	const q = `
+	SELECT
+		c.oid,
+		n.nspname,
+		c.relname,
+		c.relkind::text,
+		c.relispartition,
+		CASE WHEN c.relkind = 'p' THEN pg_catalog.pg_get_partkeydef(c.oid) END,
+		pg_catalog.pg_get_expr(c.relpartbound, c.oid),
+		pn.nspname,
+		pc.relname,
+		c.relkind IN ('r', 'p') OR (c.relkind IN ('v', 'f') AND (pg_catalog.pg_relation_is_updatable(c.oid, false) & 8) = 8),
+		pg_catalog.obj_description(c.oid, 'pg_class'),
+		CASE WHEN c.relkind IN ('v', 'm') THEN pg_catalog.pg_get_viewdef(c.oid, true) END
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_catalog.pg_inherits i
+		ON i.inhrelid = c.oid AND i.inhseqno = 1
+	LEFT JOIN pg_catalog.pg_class pc
+		ON pc.oid = i.inhparent
+	LEFT JOIN pg_catalog.pg_namespace pn
+		ON pn.oid = pc.relnamespace
+	WHERE c.relkind IN ('r', 'v', 'm', 'p', 'f')
+	AND n.nspname IN (%s)
+	ORDER BY n.nspname, c.relname`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying tables")
+	}
+	defer rows.Close()
 
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == r.TableName {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: comment for %q.%q.%q references unknown table %q",
-				r.SchemaName, r.TableName, r.ColumnName, r.TableName)
-			continue
+	var ret []tableResult
+	for rows.Next() {
+		var relkind string
+		var isPartition bool
+		var partKey, partBound, parentSchema, parent, comment, viewDef sql.NullString
+		r := tableResult{Table: &database.Table{}}
+		if err := rows.Scan(&r.OID, &r.SchemaName, &r.Table.Name, &relkind, &isPartition, &partKey, &partBound, &parentSchema, &parent, &r.Table.IsInsertable, &comment, &viewDef); err != nil {
+			return nil, errors.WithMessage(err, "error scanning table")
 		}
+		r.Table.Kind, r.Table.Type = tableKind(relkind, isPartition)
+		r.Table.IsView = relkind == "v" || relkind == "m"
+		r.Table.PartitionKey = partKey.String
+		r.Table.PartitionBound = partBound.String
+		r.Table.ParentSchema = parentSchema.String
+		r.Table.Parent = parent.String
+		r.Table.Comment = comment.String
+		r.Table.ViewDefinition = viewDef.String
+		ret = append(ret, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading tables")
+	}
+	return ret, nil
+}
 
-		for _, c := range table.Columns {
-			if c.Name == r.ColumnName {
-				c.Comment = r.Comment
-				break
-			}
-		}
+// tableKind converts a pg_class relkind into the kind of the table, and the
+// table_type information_schema would have reported for it.
+func tableKind(relkind string, isPartition bool) (kind, typ string) {
+	switch relkind {
+	case "v":
+		return database.KindView, "VIEW"
+	case "m":
+		return database.KindMaterializedView, "MATERIALIZED VIEW"
+	case "f":
+		return database.KindForeign, "FOREIGN"
+	case "p":
+		return database.KindPartitioned, "BASE TABLE"
+	}
+	if isPartition {
+		return database.KindPartition, "BASE TABLE"
 	}
+	return database.KindTable, "BASE TABLE"
+}
 
-	tableCommentResults, err := queryTableComments(log, db, schemaNames)
+type columnResult struct {
+	RelID   oid
+	Row     *columns.Row
+	Details columnDetails
+	Comment string
+}
+
+func queryColumns(log *log.Logger, db gnorm.DB, schemas []string) ([]columnResult, error) {
+	// The row values mirror the definition of information_schema.columns,
+	// which we can't use directly since it filters out materialized views and
+	// columns the current user has no privileges on.  The rest are details
+	// information_schema doesn't report: array dimensions, range subtypes, the
+	// extension a type comes from, and type modifiers such as PostGIS'
+	// geometry type and SRID.
+	//
+	// pg_range.rngmultitypid only exists from postgres 14 on, so we read it
+	// through to_jsonb, which returns null on older versions rather than
+	// failing.
+	const q = `
+	SELECT
+		c.oid,
+		pg_catalog.current_database(),
+		nc.nspname,
+		c.relname,
+		a.attname,
+		a.attnum,
+		pg_catalog.pg_get_expr(ad.adbin, ad.adrelid),
+		CASE WHEN a.attnotnull OR (t.typtype = 'd' AND t.typnotnull) THEN 'NO' ELSE 'YES' END,
+		CASE WHEN t.typtype = 'd' THEN
+			CASE WHEN bt.typelem <> 0 AND bt.typlen = -1 THEN 'ARRAY'
+				WHEN nbt.nspname = 'pg_catalog' THEN pg_catalog.format_type(t.typbasetype, NULL)
+				ELSE 'USER-DEFINED' END
+		ELSE
+			CASE WHEN t.typelem <> 0 AND t.typlen = -1 THEN 'ARRAY'
+				WHEN nt.nspname = 'pg_catalog' THEN pg_catalog.format_type(a.atttypid, NULL)
+				ELSE 'USER-DEFINED' END
+		END,
+		information_schema._pg_char_max_length(information_schema._pg_truetypid(a.*, t.*), information_schema._pg_truetypmod(a.*, t.*)),
+		information_schema._pg_numeric_precision(information_schema._pg_truetypid(a.*, t.*), information_schema._pg_truetypmod(a.*, t.*)),
+		information_schema._pg_numeric_scale(information_schema._pg_truetypid(a.*, t.*), information_schema._pg_truetypmod(a.*, t.*)),
+		information_schema._pg_datetime_precision(information_schema._pg_truetypid(a.*, t.*), information_schema._pg_truetypmod(a.*, t.*)),
+		nco.nspname,
+		co.collname,
+		CASE WHEN t.typtype = 'd' THEN nt.nspname END,
+		CASE WHEN t.typtype = 'd' THEN t.typname END,
+		COALESCE(nbt.nspname, nt.nspname),
+		COALESCE(bt.typname, t.typname),
+		CASE WHEN a.attidentity IN ('a', 'd') THEN 'YES' ELSE 'NO' END,
+		CASE a.attidentity WHEN 'a' THEN 'ALWAYS' WHEN 'd' THEN 'BY DEFAULT' END,
+		a.attndims,
+		pg_catalog.format_type(a.atttypid, a.atttypmod),
+		pg_catalog.format_type(r.rngsubtype, NULL),
+		pg_catalog.format_type(mr.rngsubtype, NULL),
+		x.extname,
+		pg_catalog.col_description(c.oid, a.attnum)
+	FROM pg_catalog.pg_attribute a
+	JOIN pg_catalog.pg_class c
+		ON c.oid = a.attrelid
+	JOIN pg_catalog.pg_namespace nc
+		ON nc.oid = c.relnamespace
+	JOIN pg_catalog.pg_type t
+		ON t.oid = a.atttypid
+	JOIN pg_catalog.pg_namespace nt
+		ON nt.oid = t.typnamespace
+	LEFT JOIN (pg_catalog.pg_type bt JOIN pg_catalog.pg_namespace nbt ON nbt.oid = bt.typnamespace)
+		ON t.typtype = 'd' AND t.typbasetype = bt.oid
+	LEFT JOIN pg_catalog.pg_attrdef ad
+		ON ad.adrelid = a.attrelid AND ad.adnum = a.attnum
+	LEFT JOIN (pg_catalog.pg_collation co JOIN pg_catalog.pg_namespace nco ON nco.oid = co.collnamespace)
+		ON co.oid = a.attcollation AND (nco.nspname <> 'pg_catalog' OR co.collname <> 'default')
+	JOIN pg_catalog.pg_type et
+		ON et.oid = CASE WHEN t.typcategory = 'A' THEN t.typelem ELSE t.oid END
+	LEFT JOIN pg_catalog.pg_range r
+		ON r.rngtypid = et.oid
+	LEFT JOIN pg_catalog.pg_range mr
+		ON (pg_catalog.to_jsonb(mr) ->> 'rngmultitypid')::oid = et.oid
+	LEFT JOIN pg_catalog.pg_depend d
+		ON d.classid = 'pg_catalog.pg_type'::regclass
+		AND d.objid = et.oid
+		AND d.refclassid = 'pg_catalog.pg_extension'::regclass
+		AND d.deptype = 'e'
+	LEFT JOIN pg_catalog.pg_extension x
+		ON x.oid = d.refobjid
+	WHERE c.relkind IN ('r', 'v', 'm', 'p', 'f')
+	AND a.attnum > 0
+	AND NOT a.attisdropped
+	AND nc.nspname IN (%s)
+	ORDER BY nc.nspname, c.relname, a.attnum`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
-		return nil, err
+		return nil, errors.WithMessage(err, "error querying columns")
 	}
-	log.Printf("found %d comments for all tables in all specified schemas", len(tableCommentResults))
+	defer rows.Close()
 
-	for _, r := range tableCommentResults {
-		if !filterTables(r.SchemaName, r.TableName) {
-			continue
+	var ret []columnResult
+	for rows.Next() {
+		r := columnResult{Row: &columns.Row{}}
+		row := r.Row
+		var subtype, multiSubtype, extension, comment sql.NullString
+		if err := rows.Scan(&r.RelID, &row.TableCatalog, &row.TableSchema, &row.TableName, &row.ColumnName, &row.OrdinalPosition, &row.ColumnDefault, &row.IsNullable, &row.DataType, &row.CharacterMaximumLength, &row.NumericPrecision, &row.NumericScale, &row.DatetimePrecision, &row.CollationSchema, &row.CollationName, &row.DomainSchema, &row.DomainName, &row.UdtSchema, &row.UdtName, &row.IsIdentity, &row.IdentityGeneration, &r.Details.Dimensions, &r.Details.FormattedType, &subtype, &multiSubtype, &extension, &comment); err != nil {
+			return nil, errors.WithMessage(err, "error scanning column")
 		}
+		r.Details.RangeSubtype = subtype.String
+		r.Details.MultirangeSubtype = multiSubtype.String
+		r.Details.Extension = extension.String
+		r.Comment = comment.String
+		ret = append(ret, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading columns")
+	}
+	return ret, nil
+}
 
-		tables, ok := schemas[r.SchemaName]
-		if !ok {
-			log.Printf("Should be impossible: comment for %q.%q references unknown schema %q",
-				r.SchemaName, r.TableName, r.SchemaName)
-			continue
-		}
+func toDBColumn(c *columns.Row, log *log.Logger) *database.Column {
+	col := &database.Column{
+		Name:              c.ColumnName.String,
+		Nullable:          c.IsNullable.String == "YES",
+		HasDefault:        c.ColumnDefault.String != "",
+		Length:            int(c.CharacterMaximumLength.Int64),
+		Precision:         int(c.NumericPrecision.Int64),
+		Scale:             int(c.NumericScale.Int64),
+		DatetimePrecision: int(c.DatetimePrecision.Int64),
+		CharacterSet:      c.CharacterSetName.String,
+		Collation:         c.CollationName.String,
+		Ordinal:           c.OrdinalPosition.Int64,
+		Orig:              *c,
+	}
 
-		var table *database.Table
-		for _, t := range tables {
-			if t.Name == r.TableName {
-				table = t
-				break
-			}
-		}
-		if table == nil {
-			log.Printf("Should be impossible: comment for %q.%q references unknown table %s",
-				r.SchemaName, r.TableName, r.TableName)
-			continue
-		}
+	col.Type, col.IsArray, col.UserDefined = pgType(c.DataType.String, c.UdtName.String)
+	if !builtinSchema(c.UdtSchema.String) {
+		col.TypeSchema = c.UdtSchema.String
+	}
 
-		table.Comment = r.Comment
+	// information_schema reports the underlying type for columns whose type is
+	// a domain, so we use the domain's name as the type instead.  The
+	// underlying type is available on the domain itself.
+	if c.DomainName.Valid {
+		col.UserDefined = true
+		col.Domain = c.DomainName.String
+		col.Type = c.DomainName.String
+		col.TypeSchema = c.DomainSchema.String
+		return col
 	}
 
-	res := &database.Info{Schemas: make([]*database.Schema, 0, len(schemas))}
-	for _, schema := range schemaNames {
-		tables := schemas[schema]
-		s := &database.Schema{
-			Name:   schema,
-			Tables: tables,
-			Enums:  enums[schema],
-		}
+	col.ColumnType = pgColumnType(col.Type, c)
+	return col
+}
 
-		dbtables := make(map[string]*database.Table, len(tables))
-		for _, t := range tables {
-			dbtables[t.Name] = t
-		}
-		for tname, index := range indexes[schema] {
-			dbtables[tname].Indexes = index
+// columnDetails holds the details of a column's type that information_schema
+// doesn't report.
+type columnDetails struct {
+	Dimensions        int
+	FormattedType     string
+	RangeSubtype      string
+	MultirangeSubtype string
+	Extension         string
+}
+
+// apply copies the column details onto the column.
+func (r columnDetails) apply(col *database.Column) {
+	if col.IsArray {
+		// columns that don't declare their dimensions (e.g. in views) report
+		// zero, but are still at least one-dimensional.
+		col.ArrayDimensions = r.Dimensions
+		if col.ArrayDimensions < 1 {
+			col.ArrayDimensions = 1
 		}
+	}
+	switch {
+	case r.RangeSubtype != "":
+		col.IsRange = true
+		col.RangeSubtype = r.RangeSubtype
+	case r.MultirangeSubtype != "":
+		col.IsMultirange = true
+		col.RangeSubtype = r.MultirangeSubtype
+	}
+	col.Extension = r.Extension
+	col.GeometryType, col.SRID = postGISTypmod(r.FormattedType)
+}
 
-		res.Schemas = append(res.Schemas, s)
+// postGISTypmod parses the geometry type and SRID out of a formatted PostGIS
+// type like geometry(Point,4326) or geography(MultiPolygonZ).  Other types
+// return an empty geometry type and zero SRID.
+func postGISTypmod(formatted string) (geometryType string, srid int) {
+	for strings.HasSuffix(formatted, "[]") {
+		formatted = strings.TrimSuffix(formatted, "[]")
+	}
+	var mods string
+	switch {
+	case strings.HasPrefix(formatted, "geometry("):
+		mods = formatted[len("geometry("):]
+	case strings.HasPrefix(formatted, "geography("):
+		mods = formatted[len("geography("):]
+	default:
+		return "", 0
+	}
+	mods = strings.TrimSuffix(mods, ")")
+	parts := strings.SplitN(mods, ",", 2)
+	geometryType = parts[0]
+	if len(parts) == 2 {
+		srid, _ = strconv.Atoi(parts[1])
 	}
+	return geometryType, srid
+}
 
-	return res, nil
+// builtinSchema reports whether the schema is one of the postgres system
+// schemas that built-in types are defined in.
+func builtinSchema(schema string) bool {
+	return schema == "" || schema == "pg_catalog" || schema == "information_schema"
 }
 
-func toDBColumn(c *columns.Row, log *log.Logger) *database.Column {
-	col := &database.Column{
-		Name:       c.ColumnName.String,
-		Nullable:   c.IsNullable.String == "YES",
-		HasDefault: c.ColumnDefault.String != "",
-		Length:     int(c.CharacterMaximumLength.Int64),
-		Ordinal:    c.OrdinalPosition.Int64,
-		Orig:       *c,
+// pgColumnType returns the declared type of a column with its modifiers, as
+// long as it has any.  information_schema only reports numeric precision and
+// scale when they were declared for numeric columns, so we don't get a
+// column type for an unconstrained numeric.
+func pgColumnType(typ string, c *columns.Row) string {
+	switch {
+	case c.DataType.String == "numeric" && c.NumericPrecision.Valid:
+		return fmt.Sprintf("%s(%d,%d)", typ, c.NumericPrecision.Int64, c.NumericScale.Int64)
+	case c.CharacterMaximumLength.Valid && c.DataType.String != "ARRAY":
+		return fmt.Sprintf("%s(%d)", typ, c.CharacterMaximumLength.Int64)
 	}
+	return ""
+}
 
-	typ := c.DataType.String
-	switch typ {
+// pgType converts the data_type and udt_name values that information_schema
+// reports for a column, attribute or domain into the type name gnorm uses, and
+// whether that type is an array or user-defined.
+func pgType(dataType, udtName string) (typ string, isArray, userDefined bool) {
+	switch dataType {
 	case "ARRAY":
-		col.IsArray = true
 		// when it's an array, postges prepends an underscore to the standard
 		// name.
-		typ = c.UdtName.String[1:]
-
+		return udtName[1:], true, false
 	case "USER-DEFINED":
-		col.UserDefined = true
-		typ = c.UdtName.String
+		return udtName, false, true
 	}
+	return dataType, false, false
+}
 
-	col.Type = typ
-
-	return col
+type primaryKeyResult struct {
+	RelID  oid
+	AttNum int64
+	Name   string
 }
 
-func queryPrimaryKeys(log *log.Logger, db *sql.DB, schemas []string) ([]*database.PrimaryKey, error) {
-	// TODO: make this work with Gnorm generated types
+func queryPrimaryKeys(log *log.Logger, db gnorm.DB, schemas []string) ([]primaryKeyResult, error) {
 	const q = `
-	SELECT k.table_schema, k.table_name, k.column_name, k.constraint_name
-	FROM information_schema.key_column_usage k
-	LEFT JOIN information_schema.table_constraints c
-    	ON k.table_schema = c.table_schema
-    	AND k.table_name = c.table_name
-    	AND k.constraint_name = c.constraint_name
-	WHERE c.constraint_type='PRIMARY KEY' AND k.table_schema IN (%s)`
-	spots := make([]string, len(schemas))
-	vals := make([]interface{}, len(schemas))
-	for x := range schemas {
-		spots[x] = fmt.Sprintf("$%v", x+1)
-		vals[x] = schemas[x]
-	}
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+	SELECT con.conrelid, k.attnum, con.conname
+	FROM pg_catalog.pg_constraint con
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = con.connamespace
+	CROSS JOIN LATERAL unnest(con.conkey) AS k(attnum)
+	WHERE con.contype = 'p'
+	AND n.nspname IN (%s)`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error querying keys")
 	}
 	defer rows.Close()
-	var ret []*database.PrimaryKey
 
+	var ret []primaryKeyResult
 	for rows.Next() {
-		kc := &database.PrimaryKey{}
-		if err := rows.Scan(&kc.SchemaName, &kc.TableName, &kc.ColumnName, &kc.Name); err != nil {
+		var r primaryKeyResult
+		if err := rows.Scan(&r.RelID, &r.AttNum, &r.Name); err != nil {
 			return nil, errors.WithMessage(err, "error scanning key constraint")
 		}
-		ret = append(ret, kc)
+		ret = append(ret, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading keys")
 	}
 	return ret, nil
 }
 
-func queryForeignKeys(log *log.Logger, db *sql.DB, schemas []string) ([]*database.ForeignKey, error) {
-	// TODO: make this work with Gnorm generated types
-	const q = `SELECT rc.constraint_schema, lkc.table_name, lkc.column_name, lkc.constraint_name, lkc.position_in_unique_constraint, fkc.table_name, fkc.column_name
-	  FROM information_schema.referential_constraints rc
-  		LEFT JOIN information_schema.key_column_usage lkc
-    	  ON lkc.table_schema = rc.constraint_schema
-      		AND lkc.constraint_name = rc.constraint_name
-  		LEFT JOIN information_schema.key_column_usage fkc
-    	  ON fkc.table_schema = rc.unique_constraint_schema
-      	    AND fkc.ordinal_position = lkc.position_in_unique_constraint
-      		AND fkc.constraint_name = rc.unique_constraint_name
-	  WHERE rc.constraint_schema IN (%s)`
-	spots := make([]string, len(schemas))
-	vals := make([]interface{}, len(schemas))
-	for x := range schemas {
-		spots[x] = fmt.Sprintf("$%v", x+1)
-		vals[x] = schemas[x]
-	}
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+type foreignKeyResult struct {
+	RelID      oid
+	AttNum     int64
+	ForeignKey *database.ForeignKey
+}
+
+func queryForeignKeys(log *log.Logger, db gnorm.DB, schemas []string) ([]foreignKeyResult, error) {
+	// conkey and confkey list the columns of the key and the referenced
+	// columns in matching order.
+	const q = `
+	SELECT
+		con.conrelid,
+		k.attnum,
+		n.nspname,
+		c.relname,
+		a.attname,
+		con.conname,
+		k.position,
+		fn.nspname,
+		fc.relname,
+		fa.attname,
+		con.condeferrable,
+		con.condeferred,
+		pg_catalog.obj_description(con.oid, 'pg_constraint')
+	FROM pg_catalog.pg_constraint con
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = con.connamespace
+	JOIN pg_catalog.pg_class c
+		ON c.oid = con.conrelid
+	JOIN pg_catalog.pg_class fc
+		ON fc.oid = con.confrelid
+	JOIN pg_catalog.pg_namespace fn
+		ON fn.oid = fc.relnamespace
+	CROSS JOIN LATERAL unnest(con.conkey, con.confkey) WITH ORDINALITY AS k(attnum, fattnum, position)
+	JOIN pg_catalog.pg_attribute a
+		ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+	JOIN pg_catalog.pg_attribute fa
+		ON fa.attrelid = con.confrelid AND fa.attnum = k.fattnum
+	WHERE con.contype = 'f'
+	AND n.nspname IN (%s)`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error querying foreign keys")
 	}
 	defer rows.Close()
-	var ret []*database.ForeignKey
 
+	var ret []foreignKeyResult
 	for rows.Next() {
-		fk := &database.ForeignKey{}
-		if err := rows.Scan(&fk.SchemaName, &fk.TableName, &fk.ColumnName, &fk.Name, &fk.UniqueConstraintPosition, &fk.ForeignTableName, &fk.ForeignColumnName); err != nil {
+		r := foreignKeyResult{ForeignKey: &database.ForeignKey{}}
+		fk := r.ForeignKey
+		var comment sql.NullString
+		if err := rows.Scan(&r.RelID, &r.AttNum, &fk.SchemaName, &fk.TableName, &fk.ColumnName, &fk.Name, &fk.UniqueConstraintPosition, &fk.ForeignSchemaName, &fk.ForeignTableName, &fk.ForeignColumnName, &fk.IsDeferrable, &fk.InitiallyDeferred, &comment); err != nil {
 			return nil, errors.WithMessage(err, "error scanning foreign key constraint")
 		}
-		ret = append(ret, fk)
+		fk.Comment = comment.String
+		ret = append(ret, r)
 	}
-	if rows.Err() != nil {
-		return nil, errors.WithMessage(rows.Err(), "error reading foreign keys")
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading foreign keys")
 	}
 	return ret, nil
 }
 
 type indexResult struct {
-	SchemaName string
-	TableName  string
-	IndexName  string
-	IsUnique   bool
-	Columns    []string
+	RelID     oid
+	IndexName string
+	IsUnique  bool
+	Columns   []string
+	Comment   string
 }
 
-func queryIndexes(log *log.Logger, db *sql.DB, schemaNames []string) ([]indexResult, error) {
+func queryIndexes(log *log.Logger, db gnorm.DB, schemaNames []string) ([]indexResult, error) {
+	// pg_get_indexdef quotes column names that need it, so we only use it for
+	// expressions, which have no attribute number, and use the raw attribute
+	// name for plain columns.  Comments on primary key, unique and exclusion
+	// constraints are on the constraint rather than its index, so an index
+	// without a comment gets its constraint's.
+	//
+	// Only the key columns are index columns; the non-key columns of an
+	// INCLUDE clause follow them in indkey.  pg_index.indnkeyatts only exists
+	// from postgres 11 on, before which every column is a key column, so we
+	// read it through to_jsonb like rngmultitypid in queryColumns.
 	const q = `
 	SELECT
-		n.nspname as schema,
-		trim(both '"' from i.indrelid::regclass::text) as table,
+		i.indrelid,
 		c.relname as name,
 		i.indisunique as is_unique,
-		array_to_string(ARRAY(
-			SELECT pg_get_indexdef(i.indexrelid, k + 1, true)
+		ARRAY(
+			SELECT COALESCE(a.attname::text, pg_get_indexdef(i.indexrelid, k + 1, true))
 			FROM generate_subscripts(i.indkey, 1) as k
+			LEFT JOIN pg_attribute a
+				ON a.attrelid = i.indrelid AND a.attnum = i.indkey[k]
+			WHERE k + 1 <= COALESCE((pg_catalog.to_jsonb(i) ->> 'indnkeyatts')::int, i.indnatts)
 			ORDER BY k
-		), ',') as column_names
+		) as column_names,
+		COALESCE(
+			pg_catalog.obj_description(i.indexrelid, 'pg_class'),
+			(
+				SELECT pg_catalog.obj_description(con.oid, 'pg_constraint')
+				FROM pg_catalog.pg_constraint con
+				WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x')
+				LIMIT 1
+			)
+		) as comment
 	FROM pg_index as i
 	JOIN pg_class as c
 		ON c.oid = i.indexrelid
 	JOIN pg_namespace as n
 		ON n.oid = c.relnamespace
 	WHERE n.nspname IN (%s)`
-
-	spots := make([]string, len(schemaNames))
-	vals := make([]interface{}, len(schemaNames))
-	for i := range schemaNames {
-		spots[i] = fmt.Sprintf("$%v", i+1)
-		vals[i] = schemaNames[i]
-	}
-
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+	spots, vals := schemaParams(schemaNames)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
 		return nil, errors.WithMessage(err, "error querying indexes")
 	}
@@ -476,192 +833,458 @@ func queryIndexes(log *log.Logger, db *sql.DB, schemaNames []string) ([]indexRes
 	var results []indexResult
 	for rows.Next() {
 		var r indexResult
-		var cs string
-		if err := rows.Scan(&r.SchemaName, &r.TableName, &r.IndexName, &r.IsUnique, &cs); err != nil {
+		var comment sql.NullString
+		if err := rows.Scan(&r.RelID, &r.IndexName, &r.IsUnique, pq.Array(&r.Columns), &comment); err != nil {
 			return nil, errors.WithMessage(err, "error scanning index")
 		}
-		r.Columns = strings.Split(cs, ",") // array converted to string in query
-
-		// postgres prepends schema onto table name if outside of public schema
-		if r.SchemaName != "public" {
-			r.TableName = r.TableName[len(r.SchemaName)+1:]
-		}
-
+		r.Comment = comment.String
 		results = append(results, r)
 	}
-
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading indexes")
+	}
 	return results, nil
 }
 
-type columnCommentResult struct {
-	SchemaName string
-	TableName  string
-	ColumnName string
-	Comment    string
-}
-
-func queryColumnComments(log *log.Logger, db *sql.DB, schemaNames []string) ([]columnCommentResult, error) {
+func queryEnums(log *log.Logger, db gnorm.DB, schemas []string) (map[string][]*database.Enum, error) {
 	const q = `
 	SELECT
-		cols.table_schema,
-		cols.table_name,
-		cols.column_name,
-			(
-					SELECT pg_catalog.col_description(c.oid, cols.ordinal_position::int)
-					FROM pg_catalog.pg_class c
-					WHERE c.relname = cols.table_name
-			) AS column_comment
-	FROM information_schema.columns cols
-	WHERE cols.table_schema IN (%s)`
-
-	spots := make([]string, len(schemaNames))
-	vals := make([]interface{}, len(schemaNames))
-	for i := range schemaNames {
-		spots[i] = fmt.Sprintf("$%v", i+1)
-		vals[i] = schemaNames[i]
-	}
-
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+		n.nspname,
+		t.typname,
+		e.enumlabel,
+		pg_catalog.obj_description(t.oid, 'pg_type')
+	FROM pg_catalog.pg_enum e
+	JOIN pg_catalog.pg_type t
+		ON t.oid = e.enumtypid
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = t.typnamespace
+	WHERE n.nspname IN (%s)
+	ORDER BY n.nspname, t.typname, e.enumsortorder`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
-		return nil, errors.WithMessage(err, "error querying column comments")
+		return nil, errors.WithMessage(err, "error querying enums")
 	}
 	defer rows.Close()
 
-	var results []columnCommentResult
+	ret := map[string][]*database.Enum{}
+	var current *database.Enum
+	var currentSchema string
+	count := 0
 	for rows.Next() {
-		var r columnCommentResult
-		var c sql.NullString
-		if err := rows.Scan(&r.SchemaName, &r.TableName, &r.ColumnName, &c); err != nil {
-			return nil, errors.WithMessage(err, "error scanning column comment")
+		var schema, name, label string
+		var comment sql.NullString
+		if err := rows.Scan(&schema, &name, &label, &comment); err != nil {
+			return nil, errors.WithMessage(err, "error scanning enum value")
 		}
 
-		if c.Valid {
-			r.Comment = c.String
-			results = append(results, r)
+		// rows are ordered by enum, so a new enum starts whenever the name
+		// changes.
+		if current == nil || currentSchema != schema || current.Name != name {
+			current = &database.Enum{Name: name, Comment: comment.String}
+			currentSchema = schema
+			ret[schema] = append(ret[schema], current)
+			count++
 		}
+		// enumsortorder is a float, since values added before or between
+		// others get fractional sort orders, so the value is the position in
+		// the enum instead, starting at 1.
+		current.Values = append(current.Values, &database.EnumValue{Name: label, Value: len(current.Values) + 1})
 	}
-
-	return results, nil
-}
-
-type tableCommentResult struct {
-	SchemaName string
-	TableName  string
-	Comment    string
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading enums")
+	}
+	log.Printf("found %d enums for all schemas", count)
+	return ret, nil
 }
 
-func queryTableComments(log *log.Logger, db *sql.DB, schemaNames []string) ([]tableCommentResult, error) {
+func queryDomains(log *log.Logger, db gnorm.DB, schemas []string) (map[string][]*database.Domain, error) {
+	// The row values mirror the definition of information_schema.domains,
+	// which we can't use directly since it filters out the domains the current
+	// user has no privileges on.
 	const q = `
 	SELECT
-		tabs.table_schema,
-		tabs.table_name,
-			(
-					SELECT obj_description(c.oid)
-					FROM pg_class c
-					WHERE c.relname = tabs.table_name
-			) AS column_comment
-	FROM information_schema.tables tabs
-	WHERE tabs.table_schema IN (%s)`
-
-	spots := make([]string, len(schemaNames))
-	vals := make([]interface{}, len(schemaNames))
-	for i := range schemaNames {
-		spots[i] = fmt.Sprintf("$%v", i+1)
-		vals[i] = schemaNames[i]
-	}
-
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+		t.oid,
+		n.nspname,
+		t.typname,
+		CASE WHEN bt.typelem <> 0 AND bt.typlen = -1 THEN 'ARRAY'
+			WHEN nbt.nspname = 'pg_catalog' THEN pg_catalog.format_type(t.typbasetype, NULL)
+			ELSE 'USER-DEFINED' END,
+		bt.typname,
+		information_schema._pg_char_max_length(t.typbasetype, t.typtypmod),
+		t.typdefault,
+		t.typnotnull
+	FROM pg_catalog.pg_type t
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = t.typnamespace
+	JOIN pg_catalog.pg_type bt
+		ON bt.oid = t.typbasetype
+	JOIN pg_catalog.pg_namespace nbt
+		ON nbt.oid = bt.typnamespace
+	WHERE t.typtype = 'd'
+	AND n.nspname IN (%s)
+	ORDER BY t.typname`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
-		return nil, errors.WithMessage(err, "error querying table comments")
+		return nil, errors.WithMessage(err, "error querying domains")
 	}
 	defer rows.Close()
 
-	var results []tableCommentResult
+	ret := map[string][]*database.Domain{}
+	byOID := map[oid]*database.Domain{}
+	count := 0
 	for rows.Next() {
-		var r tableCommentResult
-		var c sql.NullString
-		if err := rows.Scan(&r.SchemaName, &r.TableName, &c); err != nil {
-			return nil, errors.WithMessage(err, "error scanning table comment")
+		var typeOID oid
+		var schema, name, dataType, udtName string
+		var length sql.NullInt64
+		var def sql.NullString
+		var notNull bool
+		if err := rows.Scan(&typeOID, &schema, &name, &dataType, &udtName, &length, &def, &notNull); err != nil {
+			return nil, errors.WithMessage(err, "error scanning domain")
 		}
-
-		if c.Valid {
-			r.Comment = c.String
-			results = append(results, r)
+		d := &database.Domain{
+			Name:       name,
+			Length:     int(length.Int64),
+			Nullable:   !notNull,
+			HasDefault: def.String != "",
+			Default:    def.String,
 		}
+		d.BaseType, d.IsArray, d.UserDefined = pgType(dataType, udtName)
+		ret[schema] = append(ret[schema], d)
+		count++
+		byOID[typeOID] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading domains")
 	}
+	log.Printf("found %d domains for all schemas", count)
 
-	return results, nil
+	// postgres 17 and later also record NOT NULL as a domain constraint, which
+	// is already the domain's Nullable, so only check constraints are read.
+	const cq = `
+	SELECT
+		c.contypid,
+		c.conname,
+		pg_catalog.pg_get_constraintdef(c.oid, true)
+	FROM pg_catalog.pg_constraint c
+	JOIN pg_catalog.pg_type t
+		ON t.oid = c.contypid
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = t.typnamespace
+	WHERE c.contype = 'c'
+	AND n.nspname IN (%s)
+	ORDER BY c.conname`
+	crows, err := db.Query(fmt.Sprintf(cq, spots), vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying domain constraints")
+	}
+	defer crows.Close()
+	for crows.Next() {
+		var domain oid
+		c := &database.DomainConstraint{}
+		if err := crows.Scan(&domain, &c.Name, &c.Definition); err != nil {
+			return nil, errors.WithMessage(err, "error scanning domain constraint")
+		}
+		d, ok := byOID[domain]
+		if !ok {
+			log.Printf("Should be impossible: constraint %q references unknown domain %d", c.Name, domain)
+			continue
+		}
+		d.Constraints = append(d.Constraints, c)
+	}
+	if err := crows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading domain constraints")
+	}
+	return ret, nil
 }
 
-func queryEnums(log *log.Logger, db *sql.DB, schemas []string) (map[string][]*database.Enum, error) {
-	// TODO: make this work with Gnorm generated types
+func queryCompositeTypes(log *log.Logger, db gnorm.DB, schemas []string) (map[string][]*database.CompositeType, error) {
+	// The row values mirror the definition of information_schema.attributes,
+	// which we can't use directly since it filters out the types the current
+	// user has no privileges on, except that an attribute whose type is a
+	// domain reports the domain's base type, like the columns do.
 	const q = `
-	SELECT      n.nspname, t.typname as type
-	FROM        pg_type t
-	LEFT JOIN   pg_catalog.pg_namespace n ON n.oid = t.typnamespace
-	JOIN        pg_enum e ON t.oid = e.enumtypid
-	WHERE       (t.typrelid = 0 OR (SELECT c.relkind = 'c' FROM pg_catalog.pg_class c WHERE c.oid = t.typrelid))
-	AND     NOT EXISTS(SELECT 1 FROM pg_catalog.pg_type el WHERE el.oid = t.typelem AND el.typarray = t.oid)
-	AND     n.nspname IN (%s)`
-	spots := make([]string, len(schemas))
-	vals := make([]interface{}, len(schemas))
-	for x := range schemas {
-		spots[x] = fmt.Sprintf("$%v", x+1)
-		vals[x] = schemas[x]
-	}
-	query := fmt.Sprintf(q, strings.Join(spots, ", "))
-	rows, err := db.Query(query, vals...)
+	SELECT
+		nc.nspname,
+		c.relname,
+		a.attname,
+		a.attnum,
+		CASE WHEN a.attnotnull OR (t.typtype = 'd' AND t.typnotnull) THEN 'NO' ELSE 'YES' END,
+		CASE WHEN t.typtype = 'd' THEN
+			CASE WHEN bt.typelem <> 0 AND bt.typlen = -1 THEN 'ARRAY'
+				WHEN nbt.nspname = 'pg_catalog' THEN pg_catalog.format_type(t.typbasetype, NULL)
+				ELSE 'USER-DEFINED' END
+		ELSE
+			CASE WHEN t.typelem <> 0 AND t.typlen = -1 THEN 'ARRAY'
+				WHEN nt.nspname = 'pg_catalog' THEN pg_catalog.format_type(a.atttypid, NULL)
+				ELSE 'USER-DEFINED' END
+		END,
+		COALESCE(bt.typname, t.typname),
+		information_schema._pg_char_max_length(information_schema._pg_truetypid(a.*, t.*), information_schema._pg_truetypmod(a.*, t.*))
+	FROM pg_catalog.pg_attribute a
+	JOIN pg_catalog.pg_class c
+		ON c.oid = a.attrelid
+	JOIN pg_catalog.pg_namespace nc
+		ON nc.oid = c.relnamespace
+	JOIN pg_catalog.pg_type t
+		ON t.oid = a.atttypid
+	JOIN pg_catalog.pg_namespace nt
+		ON nt.oid = t.typnamespace
+	LEFT JOIN (pg_catalog.pg_type bt JOIN pg_catalog.pg_namespace nbt ON nbt.oid = bt.typnamespace)
+		ON t.typtype = 'd' AND t.typbasetype = bt.oid
+	WHERE c.relkind = 'c'
+	AND a.attnum > 0
+	AND NOT a.attisdropped
+	AND nc.nspname IN (%s)
+	ORDER BY nc.nspname, c.relname, a.attnum`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
-		return nil, errors.WithMessage(err, "error querying enum names")
+		return nil, errors.WithMessage(err, "error querying composite types")
 	}
 	defer rows.Close()
-	ret := map[string][]*database.Enum{}
+
+	ret := map[string][]*database.CompositeType{}
+	var current *database.CompositeType
+	var currentSchema string
+	count := 0
 	for rows.Next() {
-		var name, schema string
-		if err := rows.Scan(&schema, &name); err != nil {
-			return nil, errors.WithMessage(err, "error scanning enum name into string")
+		var schema, name, nullable, dataType, udtName string
+		var length sql.NullInt64
+		attr := &database.CompositeAttribute{}
+		if err := rows.Scan(&schema, &name, &attr.Name, &attr.Ordinal, &nullable, &dataType, &udtName, &length); err != nil {
+			return nil, errors.WithMessage(err, "error scanning composite type attribute")
 		}
-		vals, err := queryValues(log, db, schema, name)
-		if err != nil {
-			return nil, err
+		attr.Nullable = nullable == "YES"
+		attr.Length = int(length.Int64)
+		attr.Type, attr.IsArray, attr.UserDefined = pgType(dataType, udtName)
+
+		// rows are ordered by type, so a new type starts whenever the name
+		// changes.
+		if current == nil || currentSchema != schema || current.Name != name {
+			current = &database.CompositeType{Name: name}
+			currentSchema = schema
+			ret[schema] = append(ret[schema], current)
+			count++
 		}
-		enum := &database.Enum{
-			Name:   name,
-			Values: vals,
+		current.Attributes = append(current.Attributes, attr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading composite types")
+	}
+	log.Printf("found %d composite types for all schemas", count)
+	return ret, nil
+}
+
+func querySequences(log *log.Logger, db gnorm.DB, schemas []string) (map[string][]*database.Sequence, error) {
+	// serial columns own their sequence with an auto dependency, identity
+	// columns with an internal one.
+	const q = `
+	SELECT
+		n.nspname,
+		c.relname,
+		pg_catalog.format_type(s.seqtypid, NULL),
+		s.seqstart,
+		s.seqincrement,
+		s.seqmin,
+		s.seqmax,
+		s.seqcycle,
+		tn.nspname,
+		tc.relname,
+		a.attname
+	FROM pg_catalog.pg_sequence s
+	JOIN pg_catalog.pg_class c
+		ON c.oid = s.seqrelid
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = c.relnamespace
+	LEFT JOIN pg_catalog.pg_depend d
+		ON d.classid = 'pg_catalog.pg_class'::regclass
+		AND d.objid = c.oid
+		AND d.refclassid = 'pg_catalog.pg_class'::regclass
+		AND d.deptype IN ('a', 'i')
+	LEFT JOIN pg_catalog.pg_class tc
+		ON tc.oid = d.refobjid
+	LEFT JOIN pg_catalog.pg_namespace tn
+		ON tn.oid = tc.relnamespace
+	LEFT JOIN pg_catalog.pg_attribute a
+		ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+	WHERE n.nspname IN (%s)
+	ORDER BY c.relname`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying sequences")
+	}
+	defer rows.Close()
+
+	ret := map[string][]*database.Sequence{}
+	count := 0
+	for rows.Next() {
+		var schema string
+		var ownerSchema, ownerTable, ownerColumn sql.NullString
+		s := &database.Sequence{}
+		if err := rows.Scan(&schema, &s.Name, &s.Type, &s.Start, &s.Increment, &s.Min, &s.Max, &s.Cycle, &ownerSchema, &ownerTable, &ownerColumn); err != nil {
+			return nil, errors.WithMessage(err, "error scanning sequence")
 		}
-		ret[schema] = append(ret[schema], enum)
+		s.OwnerSchema = ownerSchema.String
+		s.OwnerTable = ownerTable.String
+		s.OwnerColumn = ownerColumn.String
+		ret[schema] = append(ret[schema], s)
+		count++
 	}
 	if err := rows.Err(); err != nil {
-		return nil, errors.WithMessage(err, "error reading enum names")
+		return nil, errors.WithMessage(err, "error reading sequences")
 	}
+	log.Printf("found %d sequences for all schemas", count)
 	return ret, nil
 }
 
-func queryValues(log *log.Logger, db *sql.DB, schema, enum string) ([]*database.EnumValue, error) {
-	// TODO: make this work with Gnorm generated types
-	rows, err := db.Query(`
+type triggerResult struct {
+	RelID   oid
+	Trigger *database.Trigger
+}
+
+func queryTriggers(log *log.Logger, db gnorm.DB, schemas []string) ([]triggerResult, error) {
+	// a trigger on a partitioned table is cloned onto each partition, and the
+	// clones are only reported with the partitioned table.  Before postgres 13
+	// clones were internal triggers; from then on they have a tgparentid,
+	// which we read through to_jsonb since older versions don't have it.
+	const q = `
 	SELECT
-	e.enumlabel,
-	e.enumsortorder
-	FROM pg_type t
-	JOIN ONLY pg_namespace n ON n.oid = t.typnamespace
-	LEFT JOIN pg_enum e ON t.oid = e.enumtypid
-	WHERE n.nspname = $1 AND t.typname = $2`, schema, enum)
+		t.tgrelid,
+		t.tgname,
+		t.tgtype,
+		pn.nspname,
+		p.proname,
+		pg_catalog.pg_get_triggerdef(t.oid, true)
+	FROM pg_catalog.pg_trigger t
+	JOIN pg_catalog.pg_class c
+		ON c.oid = t.tgrelid
+	JOIN pg_catalog.pg_namespace n
+		ON n.oid = c.relnamespace
+	JOIN pg_catalog.pg_proc p
+		ON p.oid = t.tgfoid
+	JOIN pg_catalog.pg_namespace pn
+		ON pn.oid = p.pronamespace
+	WHERE NOT t.tgisinternal
+		AND COALESCE((pg_catalog.to_jsonb(t) ->> 'tgparentid')::oid, 0) = 0
+		AND n.nspname IN (%s)
+	ORDER BY c.relname, t.tgname`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to query enum values for %s.%s", schema, enum)
+		return nil, errors.WithMessage(err, "error querying triggers")
 	}
 	defer rows.Close()
-	var vals []*database.EnumValue
+
+	var ret []triggerResult
 	for rows.Next() {
-		var name sql.NullString
-		var val sql.NullInt64
-		if err := rows.Scan(&name, &val); err != nil {
-			return nil, errors.Wrapf(err, "failed reading enum values for %s.%s", schema, enum)
+		var r triggerResult
+		var tgtype int
+		var funcSchema, funcName string
+		t := &database.Trigger{}
+		if err := rows.Scan(&r.RelID, &t.Name, &tgtype, &funcSchema, &funcName, &t.Definition); err != nil {
+			return nil, errors.WithMessage(err, "error scanning trigger")
 		}
-		vals = append(vals, &database.EnumValue{Name: name.String, Value: int(val.Int64)})
+		t.Timing, t.Events, t.ForEachRow = triggerType(tgtype)
+		t.Function = funcSchema + "." + funcName
+		r.Trigger = t
+		ret = append(ret, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading triggers")
+	}
+	return ret, nil
+}
+
+// bits of pg_trigger.tgtype, from postgres' include/catalog/pg_trigger.h.
+const (
+	triggerTypeRow      = 1 << 0
+	triggerTypeBefore   = 1 << 1
+	triggerTypeInsert   = 1 << 2
+	triggerTypeDelete   = 1 << 3
+	triggerTypeUpdate   = 1 << 4
+	triggerTypeTruncate = 1 << 5
+	triggerTypeInstead  = 1 << 6
+)
+
+// triggerType decodes the tgtype bitmask of a postgres trigger.
+func triggerType(tgtype int) (timing string, events []string, forEachRow bool) {
+	switch {
+	case tgtype&triggerTypeInstead != 0:
+		timing = "INSTEAD OF"
+	case tgtype&triggerTypeBefore != 0:
+		timing = "BEFORE"
+	default:
+		timing = "AFTER"
+	}
+	if tgtype&triggerTypeInsert != 0 {
+		events = append(events, "INSERT")
 	}
-	log.Printf("found %d values for enum %v.%v", len(vals), schema, enum)
-	return vals, nil
+	if tgtype&triggerTypeUpdate != 0 {
+		events = append(events, "UPDATE")
+	}
+	if tgtype&triggerTypeDelete != 0 {
+		events = append(events, "DELETE")
+	}
+	if tgtype&triggerTypeTruncate != 0 {
+		events = append(events, "TRUNCATE")
+	}
+	return timing, events, tgtype&triggerTypeRow != 0
+}
+
+type viewColumnResult struct {
+	RelID  oid
+	Source *database.ColumnRef
+}
+
+func queryViewColumnUsage(log *log.Logger, db gnorm.DB, schemas []string) ([]viewColumnResult, error) {
+	// information_schema.view_column_usage only shows tables owned by the
+	// current user, so we read the view's rewrite rule dependencies instead.
+	const q = `
+	SELECT DISTINCT
+		v.oid,
+		sn.nspname,
+		s.relname,
+		a.attname
+	FROM pg_catalog.pg_depend d
+	JOIN pg_catalog.pg_rewrite r
+		ON r.oid = d.objid
+	JOIN pg_catalog.pg_class v
+		ON v.oid = r.ev_class
+	JOIN pg_catalog.pg_namespace vn
+		ON vn.oid = v.relnamespace
+	JOIN pg_catalog.pg_class s
+		ON s.oid = d.refobjid
+	JOIN pg_catalog.pg_namespace sn
+		ON sn.oid = s.relnamespace
+	JOIN pg_catalog.pg_attribute a
+		ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid
+	WHERE d.classid = 'pg_catalog.pg_rewrite'::regclass
+		AND d.refclassid = 'pg_catalog.pg_class'::regclass
+		AND d.refobjsubid > 0
+		AND v.oid <> s.oid
+		AND v.relkind IN ('v', 'm')
+		AND vn.nspname IN (%s)
+	ORDER BY v.oid, sn.nspname, s.relname, a.attname`
+	spots, vals := schemaParams(schemas)
+	rows, err := db.Query(fmt.Sprintf(q, spots), vals...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error querying view column usage")
+	}
+	defer rows.Close()
+
+	var ret []viewColumnResult
+	for rows.Next() {
+		r := viewColumnResult{Source: &database.ColumnRef{}}
+		if err := rows.Scan(&r.RelID, &r.Source.Schema, &r.Source.Table, &r.Source.Column); err != nil {
+			return nil, errors.WithMessage(err, "error scanning view column usage")
+		}
+		ret = append(ret, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithMessage(err, "error reading view column usage")
+	}
+	return ret, nil
 }
@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"gnorm.org/gnorm/database"
+)
+
+func TestTableKind(t *testing.T) {
+	tests := []struct {
+		relkind     string
+		isPartition bool
+		kind        string
+		typ         string
+	}{
+		{"r", false, database.KindTable, "BASE TABLE"},
+		{"r", true, database.KindPartition, "BASE TABLE"},
+		{"p", false, database.KindPartitioned, "BASE TABLE"},
+		{"p", true, database.KindPartitioned, "BASE TABLE"},
+		{"v", false, database.KindView, "VIEW"},
+		{"m", false, database.KindMaterializedView, "MATERIALIZED VIEW"},
+		{"f", false, database.KindForeign, "FOREIGN"},
+	}
+	for _, tt := range tests {
+		kind, typ := tableKind(tt.relkind, tt.isPartition)
+		if kind != tt.kind || typ != tt.typ {
+			t.Errorf("tableKind(%q, %v) = (%q, %q), expected (%q, %q)", tt.relkind, tt.isPartition, kind, typ, tt.kind, tt.typ)
+		}
+	}
+}
+
+func TestTriggerType(t *testing.T) {
+	tests := []struct {
+		tgtype     int
+		timing     string
+		events     []string
+		forEachRow bool
+	}{
+		// BEFORE INSERT OR UPDATE ... FOR EACH ROW
+		{1 | 2 | 4 | 16, "BEFORE", []string{"INSERT", "UPDATE"}, true},
+		// AFTER DELETE ... FOR EACH STATEMENT
+		{8, "AFTER", []string{"DELETE"}, false},
+		// AFTER TRUNCATE
+		{32, "AFTER", []string{"TRUNCATE"}, false},
+		// INSTEAD OF INSERT OR UPDATE OR DELETE ... FOR EACH ROW
+		{1 | 4 | 8 | 16 | 64, "INSTEAD OF", []string{"INSERT", "UPDATE", "DELETE"}, true},
+	}
+	for _, tt := range tests {
+		timing, events, forEachRow := triggerType(tt.tgtype)
+		if timing != tt.timing {
+			t.Errorf("triggerType(%d) timing = %q, expected %q", tt.tgtype, timing, tt.timing)
+		}
+		if strings.Join(events, ",") != strings.Join(tt.events, ",") {
+			t.Errorf("triggerType(%d) events = %v, expected %v", tt.tgtype, events, tt.events)
+		}
+		if forEachRow != tt.forEachRow {
+			t.Errorf("triggerType(%d) forEachRow = %v, expected %v", tt.tgtype, forEachRow, tt.forEachRow)
+		}
+	}
+}
+
+func TestSchemaParams(t *testing.T) {
+	spots, vals := schemaParams([]string{"public", "billing"})
+	if spots != "$1, $2" {
+		t.Errorf("expected placeholders %q, got %q", "$1, $2", spots)
+	}
+	if len(vals) != 2 || vals[0] != "public" || vals[1] != "billing" {
+		t.Errorf("expected values [public billing], got %v", vals)
+	}
+}
@@ -1,6 +1,8 @@
 package database // import "gnorm.org/gnorm/database"
 import (
+	"context"
 	"log"
+	"time"
 )
 
 // Info is the collection of schema info from a database.
@@ -10,33 +12,126 @@ type Info struct {
 
 // Schema is the information on a single named schema in the database.
 type Schema struct {
-	Name   string   // the original name of the schema in the DB
-	Tables []*Table // the list of tables in this schema
-	Enums  []*Enum  // the list of enums in this schema
+	Name           string           // the original name of the schema in the DB
+	Tables         []*Table         // the list of tables in this schema
+	Enums          []*Enum          // the list of enums in this schema
+	CompositeTypes []*CompositeType // (postgres) the list of composite types in this schema
+	Domains        []*Domain        // (postgres) the list of domains in this schema
+	Sequences      []*Sequence      // (postgres) the list of sequences in this schema
 }
 
 // Enum represents a type that has a set of allowed values.
 type Enum struct {
-	Table  string       // (mysql) the original name of the table in the DB
-	Name   string       // the original name of the enum in the DB
-	Values []*EnumValue // the list of possible values for this enum
+	Table   string       // (mysql) the original name of the table in the DB
+	Name    string       // the original name of the enum in the DB
+	IsSet   bool         // (mysql) true if the enum is a SET, whose values are bit flags
+	Values  []*EnumValue // the list of possible values for this enum
+	Columns []*ColumnRef // (mysql) the columns that use this enum
+	Comment string       // (postgres) the comment attached to the enum type
 }
 
 // EnumValue is one of the named values for an enum.
 type EnumValue struct {
 	Name  string // the original label of the enum in the DB
-	Value int    // the value for this enum value (order)
+	Value int    // the value for this enum value (order, or the bit flag for a set)
 }
 
+// CompositeType is a user-defined type made up of a list of named attributes.
+type CompositeType struct {
+	Name       string                // the original name of the type in the DB
+	Attributes []*CompositeAttribute // ordered list of attributes in this type
+}
+
+// CompositeAttribute is a single named attribute of a composite type.
+type CompositeAttribute struct {
+	Name        string // the original name of the attribute in the DB
+	Type        string // the original type of the attribute in the DB
+	IsArray     bool   // true if the attribute type is an array
+	Length      int    // non-zero if the type has a length (e.g. varchar[16])
+	UserDefined bool   // true if the type is user-defined
+	Nullable    bool   // true if the attribute is not NON NULL
+	Ordinal     int64  // the attribute's ordinal position
+}
+
+// Domain is a user-defined type based on another type, with optional
+// constraints on its values.
+type Domain struct {
+	Name        string              // the original name of the domain in the DB
+	BaseType    string              // the original name of the underlying type in the DB
+	IsArray     bool                // true if the underlying type is an array
+	Length      int                 // non-zero if the underlying type has a length (e.g. varchar[16])
+	UserDefined bool                // true if the underlying type is user-defined
+	Nullable    bool                // true if the domain is not NOT NULL
+	HasDefault  bool                // true if the domain has a default
+	Default     string              // the default expression for the domain
+	Constraints []*DomainConstraint // the check constraints on the domain
+}
+
+// DomainConstraint is a check constraint attached to a domain.
+type DomainConstraint struct {
+	Name       string // the original name of the constraint in the DB
+	Definition string // the definition of the constraint (e.g. CHECK (VALUE > 0))
+}
+
+// Sequence is a generator of integer values, usually backing a serial or
+// identity column.
+type Sequence struct {
+	Name        string // the original name of the sequence in the DB
+	Type        string // the original data type of the sequence in the DB (e.g. bigint)
+	Start       int64  // the first value of the sequence
+	Increment   int64  // the amount the sequence is incremented by
+	Min         int64  // the minimum value of the sequence
+	Max         int64  // the maximum value of the sequence
+	Cycle       bool   // true if the sequence wraps around when it reaches its limit
+	OwnerSchema string // the original name of the schema of the table owning the sequence, if any; empty means the sequence's schema
+	OwnerTable  string // the original name of the table owning the sequence, if any
+	OwnerColumn string // the original name of the column owning the sequence, if any
+}
+
+// Trigger is a function or statement that is run when a table is modified.
+type Trigger struct {
+	Name       string   // the original name of the trigger in the DB
+	Timing     string   // when the trigger fires: BEFORE, AFTER, or INSTEAD OF
+	Events     []string // the events the trigger fires on: INSERT, UPDATE, DELETE, or TRUNCATE
+	ForEachRow bool     // true if the trigger fires once per row rather than once per statement
+	Function   string   // (postgres) the schema-qualified name of the function the trigger executes
+	Definition string   // the definition of the trigger (postgres) or its action statement (mysql)
+}
+
+// The kinds of tables reported in Table.Kind.
+const (
+	KindTable            = "table"             // a regular table
+	KindView             = "view"              // a view
+	KindMaterializedView = "materialized view" // (postgres) a materialized view
+	KindPartitioned      = "partitioned"       // (postgres) a partitioned table
+	KindPartition        = "partition"         // (postgres) a partition of a partitioned table
+	KindForeign          = "foreign"           // (postgres) a foreign table
+)
+
 // Table contains the definition of a database table.
 type Table struct {
-	Name         string    // the original name of the table in the DB
-	Type    string    // the table type (e.g. VIEW or BASE TABLE)
-	Comment      string    // the comment attached to the table
-	IsView       bool      // true if the table is actually a view
-	IsInsertable bool      // true if the table accepts inserts
-	Columns      []*Column // ordered list of columns in this table
-	Indexes      []*Index  // list of indexes in this table
+	Name           string       // the original name of the table in the DB
+	Type           string       // the table type (e.g. VIEW or BASE TABLE)
+	Kind           string       // the kind of table (one of the Kind constants)
+	Comment        string       // the comment attached to the table
+	IsView         bool         // true if the table is actually a view
+	IsInsertable   bool         // true if the table accepts inserts
+	PartitionKey   string       // (postgres) the partition key of a partitioned table
+	PartitionBound string       // (postgres) the partition bound of a partition
+	ParentSchema   string       // (postgres) the original name of the parent table's schema in the DB
+	Parent         string       // (postgres) the original name of the parent table in the DB, for partitions and inherited tables
+	ViewDefinition string       // the query defining the view, if the table is a view
+	SourceColumns  []*ColumnRef // the columns of other tables this view reads from, if the table is a view
+	Columns        []*Column    // ordered list of columns in this table
+	Indexes        []*Index     // list of indexes in this table
+	Triggers       []*Trigger   // list of triggers on this table
+}
+
+// ColumnRef identifies a column of a table in a schema.
+type ColumnRef struct {
+	Schema string // the original name of the schema in the DB
+	Table  string // the original name of the table in the DB
+	Column string // the original name of the column in the DB
 }
 
 // Index contains the definition of a database index.
@@ -44,6 +139,7 @@ type Index struct {
 	Name     string    // name of the index in the database
 	IsUnique bool      // true if the index is unique
 	Columns  []*Column // list of columns in this index
+	Comment  string    // (postgres) the comment attached to the index, or to its constraint
 }
 
 // PrimaryKey contains the definition of a database primary key.
@@ -61,28 +157,100 @@ type ForeignKey struct {
 	ColumnName               string // the original name of the column in the db
 	Name                     string // the original name of the foreign key constraint in the db
 	UniqueConstraintPosition int    // the position of the unique constraint in the db
+	ForeignSchemaName        string // the original name of the schema in the db for the referenced table
 	ForeignTableName         string // the original name of the table in the db for the referenced table
 	ForeignColumnName        string // the original name of the column in the db for the referenced column
+	IsDeferrable             bool   // (postgres) true if checking the constraint can be deferred to the end of the transaction
+	InitiallyDeferred        bool   // (postgres) true if checking the constraint is deferred by default
+	Comment                  string // (postgres) the comment attached to the foreign key constraint
 }
 
 // Column contains data about a column in a table.
 type Column struct {
-	Name         string      // the original name of the column in the DB
-	Type         string      // the original type of the column in the DB
-	IsArray      bool        // true if the column type is an array
-	Length       int         // non-zero if the type has a length (e.g. varchar[16])
-	UserDefined  bool        // true if the type is user-defined
-	Nullable     bool        // true if the column is not NON NULL
-	HasDefault   bool        // true if the column has a default
-	Comment      string      // the comment attached to the column
-	IsPrimaryKey bool        // true if the column is a primary key
-	Ordinal      int64       // the column's ordinal position
-	IsForeignKey bool        // true if the column is a foreign key
-	ForeignKey   *ForeignKey // foreign key database definition
-	Orig         interface{} // the raw database column data
-}
-
-// Driver defines the base interface for databases that are supported by gnorm
+	Name              string      // the original name of the column in the DB
+	Type              string      // the original type of the column in the DB
+	IsArray           bool        // true if the column type is an array
+	ArrayDimensions   int         // (postgres) the number of dimensions of an array type
+	IsRange           bool        // (postgres) true if the column type is a range type
+	IsMultirange      bool        // (postgres) true if the column type is a multirange type
+	RangeSubtype      string      // (postgres) the element type of a range or multirange type
+	Extension         string      // (postgres) the name of the extension the column type comes from, if any
+	GeometryType      string      // (postgres) the geometry type of a PostGIS geometry or geography, e.g. Point
+	SRID              int         // (postgres) the spatial reference ID of a PostGIS geometry or geography
+	Length            int         // non-zero if the type has a length (e.g. varchar[16])
+	Precision         int         // the precision of a numeric type, zero if not applicable
+	Scale             int         // the scale of a numeric type, zero if not applicable
+	DatetimePrecision int         // the fractional seconds precision of a date or time type
+	CharacterSet      string      // the character set of a character type, if any
+	Collation         string      // the collation of a character type, if any
+	ColumnType        string      // the full declared type of the column, e.g. numeric(12,2) or int(10) unsigned
+	Unsigned          bool        // (mysql) true if the numeric type is unsigned
+	Zerofill          bool        // (mysql) true if the numeric type is zerofill
+	UserDefined       bool        // true if the type is user-defined
+	TypeSchema        string      // (postgres) the original name of the schema of a user-defined type
+	Domain            string      // (postgres) the original name of the column's domain, if any
+	Nullable          bool        // true if the column is not NON NULL
+	HasDefault        bool        // true if the column has a default
+	Comment           string      // the comment attached to the column
+	IsPrimaryKey      bool        // true if the column is a primary key
+	Ordinal           int64       // the column's ordinal position
+	IsForeignKey      bool        // true if the column is a foreign key
+	ForeignKey        *ForeignKey // foreign key database definition
+	Orig              interface{} // the raw database column data
+}
+
+// Options holds the options for reading a database's schema.
+type Options struct {
+	// Schemas holds the names of the schemas to read.
+	Schemas []string
+
+	// FilterTables reports whether a table should be read.  If nil, all tables
+	// in the schemas are read.
+	FilterTables func(schema, table string) bool
+
+	// IncludeTables and ExcludeTables are the maps of schema names to table
+	// names that FilterTables was made from, for drivers that can't call
+	// FilterTables, such as external drivers.
+	IncludeTables map[string][]string
+	ExcludeTables map[string][]string
+
+	// ConnectTimeout limits how long opening each connection to the database
+	// may take.  Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// StatementTimeout limits how long the database may spend running each
+	// query.  Zero means no limit.
+	StatementTimeout time.Duration
+
+	// Snapshot, if true, reads the whole schema in a single read-only
+	// transaction with repeatable read (snapshot) isolation, so that all the
+	// queries see the same state of the catalog even if it changes while
+	// they run.
+	Snapshot bool
+
+	// Parallelism is the number of connections a driver may use to run its
+	// catalog queries concurrently.  Zero or one means the queries run one
+	// after the other on a single connection.  A driver that can't share a
+	// snapshot between connections uses a single one with Snapshot.
+	Parallelism int
+}
+
+// IncludeTable reports whether the given table should be read.
+func (o Options) IncludeTable(schema, table string) bool {
+	return o.FilterTables == nil || o.FilterTables(schema, table)
+}
+
+// Driver defines the base interface for databases that are supported by gnorm.
+// Parse should stop and return an error when the context is cancelled, and
+// release any connections it opened before returning.
 type Driver interface {
-	Parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*Info, error)
+	Parse(ctx context.Context, log *log.Logger, conn string, opts Options) (*Info, error)
+}
+
+// Configurer is implemented by drivers that need settings from gnorm.toml
+// besides the connection string, like the database/sql driver the generic
+// driver connects with.  Configure returns the driver set up with the
+// settings, or an error if they aren't valid.
+type Configurer interface {
+	Configure(settings map[string]string) (Driver, error)
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = map[string]Driver{}
+)
+
+// Register makes a driver available under the given name, which is the value
+// of DBType in gnorm.toml that selects it.  Drivers generally register
+// themselves in an init function.  Register panics if the driver is nil or a
+// driver is already registered with the name.
+func Register(name string, d Driver) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+	if d == nil {
+		panic("database: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("database: Register called twice for driver " + name)
+	}
+	drivers[name] = d
+}
+
+// Lookup returns the driver registered with the given name, if any.
+func Lookup(name string) (Driver, bool) {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	d, ok := drivers[name]
+	return d, ok
+}
+
+// Drivers returns the sorted names of the registered drivers.
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"context"
+	"log"
+	"testing"
+)
+
+type testDriver struct{}
+
+func (testDriver) Parse(ctx context.Context, log *log.Logger, conn string, opts Options) (*Info, error) {
+	return &Info{}, nil
+}
+
+func TestRegister(t *testing.T) {
+	Register("test-registry", testDriver{})
+	d, ok := Lookup("test-registry")
+	if !ok {
+		t.Fatal("registered driver not found")
+	}
+	if _, ok := d.(testDriver); !ok {
+		t.Errorf("expected testDriver, got %T", d)
+	}
+	if _, ok := Lookup("test-unregistered"); ok {
+		t.Error("expected unregistered driver not to be found")
+	}
+	found := false
+	for _, name := range Drivers() {
+		if name == "test-registry" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected test-registry in %v", Drivers())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a driver twice to panic")
+		}
+	}()
+	Register("test-registry", testDriver{})
+}
@@ -41,6 +41,7 @@ var FuncMap = map[string]interface{}{
 	"numbers":      numbers,
 	"pascal":       kace.Pascal,
 	"plural":       inflection.Plural,
+	"quoteIdent":   quoteIdent,
 	"repeat":       strings.Repeat,
 	"replace":      strings.Replace,
 	"singular":     inflection.Singular,
@@ -65,6 +66,20 @@ var FuncMap = map[string]interface{}{
 	"trimSuffix":   strings.TrimSuffix,
 }
 
+// quoteIdent quotes name as an identifier for the given database type, e.g.
+// {{quoteIdent "postgres" .Table.DBName}} or {{.Table.DBName | quoteIdent
+// "mysql"}}.  The name is always quoted, so it keeps its case and may contain
+// any character.
+func quoteIdent(dbType, name string) (string, error) {
+	switch dbType {
+	case "postgres":
+		return `"` + strings.Replace(name, `"`, `""`, -1) + `"`, nil
+	case "mysql":
+		return "`" + strings.Replace(name, "`", "``", -1) + "`", nil
+	}
+	return "", errors.Errorf("unknown database type for quoteIdent: %q", dbType)
+}
+
 // sliceString returns a slice of s from index start to end.
 func sliceString(s string, start, end int) string {
 	return s[start:end]
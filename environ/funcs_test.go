@@ -111,6 +111,21 @@ func TestConvert(t *testing.T) {
 	})
 }
 
+func TestQuoteIdent(t *testing.T) {
+	tmpl := template.Must(template.New("").Funcs(FuncMap).Parse(`{{quoteIdent "postgres" .}} {{. | quoteIdent "mysql"}}`))
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, "My\"Odd`Name.x"); err != nil {
+		t.Fatal(err)
+	}
+	expected := "\"My\"\"Odd`Name.x\" `My\"Odd``Name.x`"
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+	if _, err := quoteIdent("oracle", "name"); err == nil {
+		t.Error("expected error for unknown database type, but got none")
+	}
+}
+
 func TestMain(t *testing.M) {
 	switch os.Getenv("GO_TEST_ENV") {
 	case "command":
@@ -0,0 +1,43 @@
+package run
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultAnnotationPattern is the syntax of the annotations in comments if the
+// config doesn't set one: an @ at the start of the comment or after
+// whitespace, a key, and optionally a colon and a value, which may be double
+// quoted, like @deprecated, @validate:email or @json:"-".
+const DefaultAnnotationPattern = `(?:^|\s)@([\w.-]+)(?::(?:"([^"]*)"|(\S*)))?`
+
+// parseAnnotations finds the annotations in a comment with the given pattern,
+// whose first subexpression is the key of an annotation and whose first other
+// matching subexpression is its value.  An annotation without a value has the
+// value "true", and later annotations with the same key replace earlier ones.
+// It returns the comment without its annotations, and the annotations, which
+// are nil if there are none or the pattern is nil.
+func parseAnnotations(pattern *regexp.Regexp, comment string) (string, map[string]string) {
+	if pattern == nil {
+		return comment, nil
+	}
+	matches := pattern.FindAllStringSubmatchIndex(comment, -1)
+	if len(matches) == 0 {
+		return comment, nil
+	}
+	annotations := make(map[string]string, len(matches))
+	for _, m := range matches {
+		if len(m) < 4 || m[2] == -1 {
+			continue
+		}
+		value := "true"
+		for x := 4; x < len(m); x += 2 {
+			if m[x] != -1 {
+				value = comment[m[x]:m[x+1]]
+				break
+			}
+		}
+		annotations[comment[m[2]:m[3]]] = value
+	}
+	return strings.TrimSpace(pattern.ReplaceAllLiteralString(comment, "")), annotations
+}
@@ -0,0 +1,111 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"testing"
+	"text/template"
+
+	"github.com/google/go-cmp/cmp"
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	def := regexp.MustCompile(DefaultAnnotationPattern)
+	tests := []struct {
+		pattern     *regexp.Regexp
+		comment     string
+		description string
+		annotations map[string]string
+	}{
+		{def, "", "", nil},
+		{def, "Just a comment.", "Just a comment.", nil},
+		{def, "Login email. @validate:email @json:\"-\"", "Login email.", map[string]string{"validate": "email", "json": "-"}},
+		{def, "@deprecated Use name instead.", "Use name instead.", map[string]string{"deprecated": "true"}},
+		{def, "Contact @db.tag:\"a b\" person", "Contact person", map[string]string{"db.tag": "a b"}},
+		{def, "@default:\"\" @a:1 @a:2", "", map[string]string{"default": "", "a": "2"}},
+		{def, "Mail me at bob@example.com", "Mail me at bob@example.com", nil},
+		{regexp.MustCompile(`\s*\[(\w+)(?:=(\w+))?\]`), "Price [unit=cents] [money]", "Price", map[string]string{"unit": "cents", "money": "true"}},
+		{nil, "Not parsed @json:\"-\"", "Not parsed @json:\"-\"", nil},
+	}
+	for _, test := range tests {
+		description, annotations := parseAnnotations(test.pattern, test.comment)
+		if description != test.description {
+			t.Errorf("%q: expected description %q, got %q", test.comment, test.description, description)
+		}
+		if diff := cmp.Diff(test.annotations, annotations); diff != "" {
+			t.Errorf("%q: unexpected annotations (-want +got):\n%s", test.comment, diff)
+		}
+	}
+}
+
+func TestMakeDataAnnotations(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion:    template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		AnnotationPattern: regexp.MustCompile(DefaultAnnotationPattern),
+	}
+	fk := fkColumn("user_id", "posts", "users")
+	fk.ForeignKey.Comment = "@cascade"
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Enums: []*database.Enum{{
+				Name:    "mood",
+				Comment: "How a user feels. @go.type:Mood",
+				Values:  []*database.EnumValue{{Name: "happy", Value: 1}},
+			}},
+			Tables: []*database.Table{{
+				Name:    "users",
+				Comment: "@table.public",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name: "posts",
+				Columns: []*database.Column{
+					idColumn(),
+					fk,
+					{Name: "title", Type: "text", Comment: `The title. @json:"-" @validate:required`},
+				},
+				Indexes: []*database.Index{{
+					Name:    "posts_title_idx",
+					Columns: []*database.Column{{Name: "title"}},
+					Comment: "@search",
+				}},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	users, posts := sch.TablesByName["users"], sch.TablesByName["posts"]
+
+	if users.Comment != "@table.public" || users.Description != "" || users.Annotations["table.public"] != "true" {
+		t.Errorf("unexpected table comment %q, description %q and annotations %v", users.Comment, users.Description, users.Annotations)
+	}
+	title := posts.ColumnsByName["title"]
+	if diff := cmp.Diff(map[string]string{"json": "-", "validate": "required"}, title.Annotations); diff != "" {
+		t.Errorf("unexpected column annotations (-want +got):\n%s", diff)
+	}
+	if title.Description != "The title." {
+		t.Errorf("expected column description %q, got %q", "The title.", title.Description)
+	}
+	if id := posts.ColumnsByName["id"]; id.Description != "" || id.Annotations != nil {
+		t.Errorf("expected no description or annotations without a comment, got %q and %v", id.Description, id.Annotations)
+	}
+	if idx := posts.IndexesByName["posts_title_idx"]; idx.Annotations["search"] != "true" {
+		t.Errorf("expected index annotation search, got %v", idx.Annotations)
+	}
+	if fk := posts.FKByName["posts_user_id_fkey"]; fk.Annotations["cascade"] != "true" {
+		t.Errorf("expected foreign key annotation cascade, got %v", fk.Annotations)
+	}
+	mood := sch.EnumsByName["mood"]
+	if mood.Description != "How a user feels." || mood.Annotations["go.type"] != "Mood" {
+		t.Errorf("unexpected enum description %q and annotations %v", mood.Description, mood.Annotations)
+	}
+}
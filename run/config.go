@@ -1,7 +1,9 @@
 package run
 
 import (
+	"regexp"
 	"text/template"
+	"time"
 
 	"gnorm.org/gnorm/database"
 	"gnorm.org/gnorm/run/data"
@@ -43,10 +45,85 @@ type Config struct {
 	// "{{pascal .}}".
 	NameConversion *template.Template
 
+	// SchemaNameConversion, TableNameConversion, ColumnNameConversion,
+	// EnumNameConversion, EnumValueNameConversion, IndexNameConversion,
+	// ForeignKeyNameConversion and RelationNameConversion, if not nil,
+	// override NameConversion for that kind of item.  Rather than the DB name,
+	// they are passed the item's .DBName, and depending on the kind of item,
+	// its .Schema, .Table, .Column, .Enum, .ForeignKey or .Relation.
+	SchemaNameConversion     *template.Template
+	TableNameConversion      *template.Template
+	ColumnNameConversion     *template.Template
+	EnumNameConversion       *template.Template
+	EnumValueNameConversion  *template.Template
+	IndexNameConversion      *template.Template
+	ForeignKeyNameConversion *template.Template
+	RelationNameConversion   *template.Template
+
+	// NameCollisions is how converted names that collide with another name in
+	// the same scope, or are one of ReservedWords, are handled: CollisionWarn
+	// (the default if empty), CollisionError or CollisionSuffix.
+	NameCollisions string
+
+	// ReservedWords are the words converted names may not be.
+	ReservedWords []string
+
+	// Strict, if true, makes generation fail if there are any unmapped types,
+	// unresolved references, skipped indexes, empty converted names, or names
+	// that collide or are reserved words and aren't suffixed, with an error
+	// listing all of them.  Otherwise they're only logged.
+	Strict bool
+
+	// Overrides adjust single tables and columns, by the schema, table and
+	// column they apply to.
+	Overrides map[OverrideKey]Override
+
+	// MySQLEnumNaming defines the DBName of mysql enums and sets.  This is a
+	// template that may use all the regular functions, and may reference the
+	// values .Schema, .Table and .Column, containing the original names of the
+	// column's schema, table and column.  If nil, an enum is named after its
+	// column.
+	MySQLEnumNaming *template.Template
+
+	// ArrayType defines the Type of array columns whose type isn't in the
+	// type maps with its array shape (e.g. "int4[]").  This is a template that
+	// may use all the regular functions, and may reference the values .Type,
+	// the element type mapped with the TypeMap, .DBType, the original element
+	// type, .Dimensions, the number of dimensions of the array, .Nullable, and
+	// .TypeMap, the name of the named type map, empty for the default maps.
+	// If nil, arrays are mapped by their element type.
+	ArrayType *template.Template
+
+	// TypeRules map the types of columns before the type maps do.  The first
+	// rule that matches a column sets its Type.
+	TypeRules []TypeRule
+
+	// AnnotationPattern finds the annotations in the comments of tables,
+	// columns, enums, indexes and foreign keys.  Its first subexpression is
+	// the key of an annotation, and its first other matching subexpression is
+	// the value.  If nil, comments aren't parsed.
+	AnnotationPattern *regexp.Regexp
+
 	// Driver holds a reference to the current database driver that was
 	// registered for the DBType and can connect using ConnStr.
 	Driver database.Driver
 
+	// ConnectTimeout limits how long connecting to the database may take.
+	// Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// StatementTimeout limits how long the database may spend running each
+	// query that reads the schema.  Zero means no limit.
+	StatementTimeout time.Duration
+
+	// Parallelism is the number of database connections the driver may use to
+	// run its catalog queries concurrently.
+	Parallelism int
+
+	// Snapshot, if true, makes the driver read the whole schema in read-only
+	// transactions that all see the same state of the catalog.
+	Snapshot bool
+
 	// Params contains any data you may want to pass to your templates.  This is
 	// a good way to make templates reusable with different configuration values
 	// for different situations.  The values in this field will be available in
@@ -71,6 +148,10 @@ type Config struct {
 		// If true, the output of the tool will be written to the target file.
 		UseStdout bool
 	}
+
+	// typeMap is the name of the named type map whose maps are the TypeMap
+	// and NullableTypeMap of this config, if it was made by forTypeMap.
+	typeMap string
 }
 
 // OutputTarget contains a template that generates a filename to write to, and a
@@ -81,4 +162,82 @@ type OutputTarget struct {
 	Filename     *template.Template
 	Contents     *template.Template
 	ContentsPath string
+
+	// TypeMap is the name of the type map whose types are the Type of the
+	// columns while this target is rendered.  If empty, it's the default
+	// TypeMap and NullableTypeMap.
+	TypeMap string
+}
+
+// OverrideKey identifies the table or column an Override applies to.  Column
+// is empty for a table.
+type OverrideKey struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+// Override adjusts the data of a single table or column.  Only Name and Params
+// apply to tables.
+type Override struct {
+	// Name, if not empty, replaces the converted name.
+	Name string
+
+	// Type, if not empty, replaces the type mapped from the TypeMap or
+	// NullableTypeMap.
+	Type string
+
+	// Types, by the name of a named type map, replace Type in the types from
+	// that map.
+	Types map[string]string
+
+	// Exclude leaves the column out of the data.  Indexes that include the
+	// column, and foreign keys that include or reference it, are left out
+	// too.
+	Exclude bool
+
+	// Nullable, if not nil, replaces whether the column is nullable, before
+	// its type is mapped.
+	Nullable *bool
+
+	// PrimaryKey makes the column part of the primary key, which is mostly
+	// useful for views, which don't have one.
+	PrimaryKey bool
+
+	// Params is put in the Params of the table or column.
+	Params map[string]interface{}
+}
+
+// TypeRule maps the type of the columns it matches.  A column matches if it
+// matches every pattern and value of the rule that isn't nil.
+type TypeRule struct {
+	// Name identifies the rule in the TypeSource of the columns it maps.
+	Name string
+
+	// DBType matches any of the keys the column's type may be mapped with in
+	// the TypeMap, e.g. "numeric(12,2)" or "numeric".
+	DBType *regexp.Regexp
+
+	// Schema, Table and Column match the DB names of the column's schema,
+	// table and column.
+	Schema *regexp.Regexp
+	Table  *regexp.Regexp
+	Column *regexp.Regexp
+
+	// Length, Precision and Scale match the column's exactly.
+	Length    *int
+	Precision *int
+	Scale     *int
+
+	// Array and Nullable match whether the column is an array and nullable.
+	// Arrays only match if Array is true.
+	Array    *bool
+	Nullable *bool
+
+	// Type is the Type of the columns the rule matches.
+	Type string
+
+	// Types, by the name of a named type map, replace Type in the types from
+	// that map.
+	Types map[string]string
 }
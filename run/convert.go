@@ -2,21 +2,47 @@ package run
 
 import (
 	"bytes"
-	"log"
+	"fmt"
+	"strings"
+	"text/template"
 
 	"github.com/pkg/errors"
 	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
 	"gnorm.org/gnorm/run/data"
 )
 
-type nameConverter func(s string) (string, error)
+// nameData is the data passed to the name conversion templates for each kind
+// of item.  Only the fields that apply to the kind of item are set.
+type nameData struct {
+	DBName     string           // the original name of the item
+	Schema     *data.Schema     // the schema the item is in
+	Table      *data.Table      // the table being named, or the table the item belongs to
+	Column     *data.Column     // the column being named
+	Enum       *data.Enum       // the enum being named, or the enum of the value being named
+	ForeignKey *data.ForeignKey // the foreign key being named
+	Relation   *data.Relation   // the relation being named
+}
+
+// nameConverter converts the DBName of an item into its Name with the given
+// template, or with NameConversion if the template is nil.
+type nameConverter func(t *template.Template, d nameData) (string, error)
 
-func makeData(log *log.Logger, info *database.Info, cfg *Config) (*data.DBData, error) {
-	convert := func(s string) (string, error) {
+// makeData converts the database info into the template data.  Problems are
+// logged to env.Log, and name collision warnings are written to env.Stderr, so
+// that they're seen without -verbose.
+func makeData(env environ.Values, info *database.Info, cfg *Config) (*data.DBData, error) {
+	log := env.Log
+	convert := func(t *template.Template, d nameData) (string, error) {
 		buf := &bytes.Buffer{}
-		err := cfg.NameConversion.Execute(buf, s)
+		var err error
+		if t != nil {
+			err = t.Execute(buf, d)
+		} else {
+			err = cfg.NameConversion.Execute(buf, d.DBName)
+		}
 		if err != nil {
-			return "", errors.WithMessage(err, "name conversion failed for "+s)
+			return "", errors.WithMessage(err, "name conversion failed for "+d.DBName)
 		}
 		return buf.String(), nil
 	}
@@ -24,29 +50,51 @@ func makeData(log *log.Logger, info *database.Info, cfg *Config) (*data.DBData,
 	db := &data.DBData{
 		SchemasByName: make(map[string]*data.Schema, len(info.Schemas)),
 	}
+	probs := &problems{log: log}
 	var err error
+	// the enums of each schema, after merging.
+	schemaEnums := make(map[string][]*database.Enum, len(info.Schemas))
 	for _, s := range info.Schemas {
 		sch := &data.Schema{
-			DBName:       s.Name,
-			TablesByName: make(map[string]*data.Table, len(s.Tables)),
+			DBName:               s.Name,
+			TablesByName:         make(map[string]*data.Table, len(s.Tables)),
+			EnumsByName:          make(map[string]*data.Enum, len(s.Enums)),
+			CompositeTypesByName: make(map[string]*data.CompositeType, len(s.CompositeTypes)),
+			DomainsByName:        make(map[string]*data.Domain, len(s.Domains)),
+			SequencesByName:      make(map[string]*data.Sequence, len(s.Sequences)),
 		}
 		db.Schemas = append(db.Schemas, sch)
 		db.SchemasByName[sch.DBName] = sch
 
-		sch.Name, err = convert(s.Name)
+		sch.Name, err = convert(cfg.SchemaNameConversion, nameData{DBName: s.Name, Schema: sch})
 		if err != nil {
 			return nil, errors.WithMessage(err, "schema")
 		}
-		for _, e := range s.Enums {
+		enums := s.Enums
+		if cfg.MergeMySQLEnums {
+			enums = mergeEnums(enums)
+		}
+		schemaEnums[s.Name] = enums
+		for _, e := range enums {
 			enum := &data.Enum{
 				DBName: e.Name,
 				Schema: sch,
 				Table: &data.Table{
 					DBName: e.Table,
 				},
+				IsSet:   e.IsSet,
+				Comment: e.Comment,
+			}
+			enum.Description, enum.Annotations = parseAnnotations(cfg.AnnotationPattern, e.Comment)
+			if e.Table != "" && cfg.MySQLEnumNaming != nil {
+				enum.DBName, err = mysqlEnumName(cfg, s.Name, e)
+				if err != nil {
+					return nil, err
+				}
 			}
 			sch.Enums = append(sch.Enums, enum)
-			enum.Name, err = convert(e.Name)
+			sch.EnumsByName[enum.DBName] = enum
+			enum.Name, err = convert(cfg.EnumNameConversion, nameData{DBName: enum.DBName, Schema: sch, Enum: enum})
 			if err != nil {
 				return nil, errors.WithMessage(err, "enum")
 			}
@@ -56,79 +104,206 @@ func makeData(log *log.Logger, info *database.Info, cfg *Config) (*data.DBData,
 					Value:  v.Value,
 				}
 				enum.Values = append(enum.Values, val)
-				val.Name, err = convert(v.Name)
+				val.Name, err = convert(cfg.EnumValueNameConversion, nameData{DBName: v.Name, Schema: sch, Enum: enum})
 				if err != nil {
 					return nil, errors.WithMessage(err, "enum value")
 				}
 			}
 		}
+		for _, d := range s.Domains {
+			domain := &data.Domain{
+				DBName:      d.Name,
+				Schema:      sch,
+				BaseType:    d.BaseType,
+				IsArray:     d.IsArray,
+				Length:      d.Length,
+				UserDefined: d.UserDefined,
+				Nullable:    d.Nullable,
+				HasDefault:  d.HasDefault,
+				Default:     d.Default,
+			}
+			sch.Domains = append(sch.Domains, domain)
+			sch.DomainsByName[domain.DBName] = domain
+			domain.Name, err = convert(nil, nameData{DBName: d.Name})
+			if err != nil {
+				return nil, errors.WithMessage(err, "domain")
+			}
+			var ok bool
+			domain.Type, ok = mapType(cfg, d.BaseType, d.Nullable)
+			if !ok {
+				probs.add("Unmapped type %v of domain %v.%v", d.BaseType, s.Name, d.Name)
+			}
+			for _, c := range d.Constraints {
+				con := &data.DomainConstraint{
+					DBName:     c.Name,
+					Definition: c.Definition,
+				}
+				domain.Constraints = append(domain.Constraints, con)
+				con.Name, err = convert(nil, nameData{DBName: c.Name})
+				if err != nil {
+					return nil, errors.WithMessage(err, "domain constraint")
+				}
+			}
+		}
+		for _, ct := range s.CompositeTypes {
+			comp := &data.CompositeType{
+				DBName: ct.Name,
+				Schema: sch,
+			}
+			sch.CompositeTypes = append(sch.CompositeTypes, comp)
+			sch.CompositeTypesByName[comp.DBName] = comp
+			comp.Name, err = convert(nil, nameData{DBName: ct.Name})
+			if err != nil {
+				return nil, errors.WithMessage(err, "composite type")
+			}
+			for _, a := range ct.Attributes {
+				attr := &data.CompositeAttribute{
+					DBName:      a.Name,
+					DBType:      a.Type,
+					IsArray:     a.IsArray,
+					Length:      a.Length,
+					UserDefined: a.UserDefined,
+					Nullable:    a.Nullable,
+					Ordinal:     a.Ordinal,
+				}
+				comp.Attributes = append(comp.Attributes, attr)
+				attr.Name, err = convert(nil, nameData{DBName: a.Name})
+				if err != nil {
+					return nil, errors.WithMessage(err, "composite type attribute")
+				}
+				var ok bool
+				attr.Type, ok = mapType(cfg, a.Type, a.Nullable)
+				if !ok {
+					probs.add("Unmapped type %v of attribute %v of composite type %v.%v", a.Type, a.Name, s.Name, ct.Name)
+				}
+			}
+		}
+	}
+	// columns may use types from other schemas, so tables can only be made
+	// once all the types exist.
+	for _, s := range info.Schemas {
+		sch := db.SchemasByName[s.Name]
 		for _, t := range s.Tables {
 			table := &data.Table{
-				DBName:        t.Name,
-				Type:          t.Type,
-				Comment:       t.Comment,
-				IsView:        t.IsView,
-				IsInsertable:  t.IsInsertable,
-				Schema:        sch,
-				ColumnsByName: make(map[string]*data.Column, len(t.Columns)),
-				IndexesByName: make(map[string]*data.Index, len(t.Indexes)),
-				FKByName:      map[string]*data.ForeignKey{},
-				FKRefsByName:  map[string]*data.ForeignKey{},
+				DBName:         t.Name,
+				Type:           t.Type,
+				Kind:           t.Kind,
+				Comment:        t.Comment,
+				IsView:         t.IsView,
+				IsInsertable:   t.IsInsertable,
+				PartitionKey:   t.PartitionKey,
+				PartitionBound: t.PartitionBound,
+				ViewDefinition: t.ViewDefinition,
+				Schema:         sch,
+				ColumnsByName:  make(map[string]*data.Column, len(t.Columns)),
+				IndexesByName:  make(map[string]*data.Index, len(t.Indexes)),
+				FKByName:       map[string]*data.ForeignKey{},
+				FKRefsByName:   map[string]*data.ForeignKey{},
 			}
+			table.Description, table.Annotations = parseAnnotations(cfg.AnnotationPattern, t.Comment)
 			sch.Tables = append(sch.Tables, table)
 			sch.TablesByName[table.DBName] = table
-			table.Name, err = convert(t.Name)
+			table.Name, err = convert(cfg.TableNameConversion, nameData{DBName: t.Name, Schema: sch, Table: table})
 			if err != nil {
 				return nil, errors.WithMessage(err, "table")
 			}
+			tov := cfg.override(s.Name, t.Name, "")
+			if tov.Name != "" {
+				table.Name = tov.Name
+			}
+			table.Params = tov.Params
 			for _, c := range t.Columns {
+				ov := cfg.override(s.Name, t.Name, c.Name)
+				if ov.Exclude {
+					continue
+				}
 				col := &data.Column{
 					Table:              table,
 					DBName:             c.Name,
 					DBType:             c.Type,
 					IsArray:            c.IsArray,
+					ArrayDimensions:    c.ArrayDimensions,
+					IsRange:            c.IsRange,
+					IsMultirange:       c.IsMultirange,
+					RangeSubtype:       c.RangeSubtype,
+					Extension:          c.Extension,
+					GeometryType:       c.GeometryType,
+					SRID:               c.SRID,
 					Length:             c.Length,
+					Precision:          c.Precision,
+					Scale:              c.Scale,
+					DatetimePrecision:  c.DatetimePrecision,
+					CharacterSet:       c.CharacterSet,
+					Collation:          c.Collation,
+					ColumnType:         c.ColumnType,
+					Unsigned:           c.Unsigned,
+					Zerofill:           c.Zerofill,
 					UserDefined:        c.UserDefined,
+					TypeSchema:         c.TypeSchema,
 					Nullable:           c.Nullable,
 					HasDefault:         c.HasDefault,
 					Comment:            c.Comment,
-					IsPrimaryKey:       c.IsPrimaryKey,
+					IsPrimaryKey:       c.IsPrimaryKey || ov.PrimaryKey,
 					Ordinal:            c.Ordinal,
 					IsFK:               c.IsForeignKey,
 					FKColumnRefsByName: map[string]*data.ForeignKeyColumn{},
+					Params:             ov.Params,
 					Orig:               c.Orig,
 				}
+				col.Description, col.Annotations = parseAnnotations(cfg.AnnotationPattern, c.Comment)
 				table.Columns = append(table.Columns, col)
 				table.ColumnsByName[col.DBName] = col
-				col.Name, err = convert(c.Name)
+				if c.UserDefined || c.TypeSchema != "" {
+					mapColumnUserType(probs, c, col, sch, db)
+				}
+				if col.Domain != nil && !col.Domain.Nullable {
+					// a NOT NULL domain makes the column non-nullable too.
+					col.Nullable = false
+				}
+				if ov.Nullable != nil {
+					col.Nullable = *ov.Nullable
+				}
+				if err = setColumnType(probs, cfg, col, ov); err != nil {
+					return nil, err
+				}
+				if err = setColumnTypes(probs, cfg, col, ov); err != nil {
+					return nil, err
+				}
+				// columns are named once their type is known, so the
+				// template can use it.
+				col.Name, err = convert(cfg.ColumnNameConversion, nameData{DBName: c.Name, Schema: sch, Table: table, Column: col})
 				if err != nil {
 					return nil, errors.WithMessage(err, "column")
 				}
-				var ok bool
-				if c.Nullable {
-					col.Type, ok = cfg.NullableTypeMap[c.Type]
-					if !ok {
-						log.Println("Unmapped nullable type:", c.Type)
-					}
-				} else {
-					col.Type, ok = cfg.TypeMap[c.Type]
-					if !ok {
-						log.Println("Unmapped type:", c.Type)
-					}
+				if ov.Name != "" {
+					col.Name = ov.Name
 				}
 			}
 			table.PrimaryKeys = filterPrimaryKeyColumns(table.Columns)
 
+		indexes:
 			for _, i := range t.Indexes {
+				for _, c := range i.Columns {
+					if cfg.override(s.Name, t.Name, c.Name).Exclude {
+						continue indexes
+					}
+				}
 				index := &data.Index{
 					DBName:   i.Name,
 					IsUnique: i.IsUnique,
+					Comment:  i.Comment,
 				}
+				index.Description, index.Annotations = parseAnnotations(cfg.AnnotationPattern, i.Comment)
 				for _, c := range i.Columns {
-					index.Columns = append(index.Columns, table.ColumnsByName[c.Name])
+					col, ok := table.ColumnsByName[c.Name]
+					if !ok {
+						probs.add("Skipped index %v of table %v.%v, which references unknown column %v", i.Name, s.Name, t.Name, c.Name)
+						continue indexes
+					}
+					index.Columns = append(index.Columns, col)
 				}
 
-				index.Name, err = convert(i.Name)
+				index.Name, err = convert(cfg.IndexNameConversion, nameData{DBName: i.Name, Schema: sch, Table: table})
 				if err != nil {
 					return nil, errors.WithMessage(err, "index")
 				}
@@ -136,14 +311,443 @@ func makeData(log *log.Logger, info *database.Info, cfg *Config) (*data.DBData,
 				table.Indexes = append(table.Indexes, index)
 				table.IndexesByName[index.DBName] = index
 			}
+
+			for _, tr := range t.Triggers {
+				trigger := &data.Trigger{
+					DBName:     tr.Name,
+					Table:      table,
+					Timing:     tr.Timing,
+					Events:     tr.Events,
+					ForEachRow: tr.ForEachRow,
+					Function:   tr.Function,
+					Definition: tr.Definition,
+				}
+				trigger.Name, err = convert(nil, nameData{DBName: tr.Name})
+				if err != nil {
+					return nil, errors.WithMessage(err, "trigger")
+				}
+				table.Triggers = append(table.Triggers, trigger)
+			}
 		}
-		if err = mapSchemaForeignKeyReferences(s, sch, convert); err != nil {
+		for _, sq := range s.Sequences {
+			seq := &data.Sequence{
+				DBName:       sq.Name,
+				Schema:       sch,
+				DBType:       sq.Type,
+				Start:        sq.Start,
+				Increment:    sq.Increment,
+				Min:          sq.Min,
+				Max:          sq.Max,
+				Cycle:        sq.Cycle,
+				TableDBName:  sq.OwnerTable,
+				ColumnDBName: sq.OwnerColumn,
+			}
+			if sq.OwnerTable != "" {
+				seq.TableSchemaDBName = sq.OwnerSchema
+				if seq.TableSchemaDBName == "" {
+					seq.TableSchemaDBName = s.Name
+				}
+			}
+			sch.Sequences = append(sch.Sequences, seq)
+			sch.SequencesByName[seq.DBName] = seq
+			seq.Name, err = convert(nil, nameData{DBName: sq.Name})
+			if err != nil {
+				return nil, errors.WithMessage(err, "sequence")
+			}
+		}
+		// sch.Enums is in the same order as the schema's enums.
+		for x, e := range schemaEnums[s.Name] {
+			mapEnumColumns(probs, cfg, e, sch.Enums[x], sch)
+		}
+	}
+	// sequences and foreign keys may belong to or reference tables in other
+	// schemas, so they can only be mapped once all the schemas exist.
+	mapSequenceOwners(db)
+	for _, s := range info.Schemas {
+		if err = mapSchemaForeignKeyReferences(probs, cfg, s, db, convert); err != nil {
+			return nil, err
+		}
+	}
+	if err = mapRelations(cfg, db, convert); err != nil {
+		return nil, err
+	}
+	orderTables(log, db)
+	mapParentTables(probs, info, db)
+	if err = mapViewSources(probs, cfg, info, db); err != nil {
+		return nil, err
+	}
+	checkOverrides(probs, cfg, info)
+	setImports(cfg, db)
+	if err = checkNames(env.Stderr, probs, cfg, db); err != nil {
+		return nil, err
+	}
+	if cfg.Strict {
+		if err = probs.err(); err != nil {
 			return nil, err
 		}
 	}
 	return db, nil
 }
 
+// mapColumnUserType links a column to the domain, composite type or enum that
+// is its type.  The type may be in another schema, in which case that schema
+// must be one of the schemas that were read.
+func mapColumnUserType(probs *problems, c *database.Column, col *data.Column, sch *data.Schema, db *data.DBData) {
+	typeSchema := sch
+	if c.TypeSchema != "" {
+		var ok bool
+		typeSchema, ok = db.SchemasByName[c.TypeSchema]
+		if !ok {
+			probs.add("Unmapped schema %v for type %v of column %v.%v.%v", c.TypeSchema, c.Type, sch.DBName, col.Table.DBName, col.DBName)
+			return
+		}
+	}
+	if c.Domain != "" {
+		col.Domain = typeSchema.DomainsByName[c.Domain]
+	}
+	col.CompositeType = typeSchema.CompositeTypesByName[c.Type]
+	if enum, ok := typeSchema.EnumsByName[c.Type]; ok {
+		col.Enum = enum
+		enum.Columns = append(enum.Columns, col)
+	}
+}
+
+// arrayTypeData is the data passed to the ArrayType template.
+type arrayTypeData struct {
+	Type       string
+	DBType     string
+	Dimensions int
+	Nullable   bool
+	TypeMap    string
+}
+
+// setColumnType sets the column's Type and TypeSource with columnType, and
+// reports the column if its type is unmapped.
+func setColumnType(probs *problems, cfg *Config, col *data.Column, ov Override) error {
+	var ok bool
+	var err error
+	col.Type, col.TypeSource, _, ok, err = columnType(cfg, col, ov)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if col.Nullable {
+			probs.add("Unmapped nullable type %v of column %v.%v.%v", col.DBType, col.Table.Schema.DBName, col.Table.DBName, col.DBName)
+		} else {
+			probs.add("Unmapped type %v of column %v.%v.%v", col.DBType, col.Table.Schema.DBName, col.Table.DBName, col.DBName)
+		}
+	}
+	return nil
+}
+
+// columnType returns the column's type and what it was mapped with: the
+// column's override, the first type rule that matches it, or else the type
+// maps, falling back to the underlying type of the column's domain.  Arrays
+// are first looked up with their shape (e.g. "int4[]"), then with the
+// ArrayType template.  ok is false if the type is unmapped.
+//
+// key is the type map key the type was mapped with, or, for the ArrayType
+// template, the key with the array's shape that would map it directly.  It's
+// empty if the type came from an override or a type rule, or is unmapped.
+func columnType(cfg *Config, col *data.Column, ov Override) (typ, source, key string, ok bool, err error) {
+	if typ := ov.typeIn(cfg.typeMap); typ != "" {
+		return typ, "Overrides", "", true, nil
+	}
+	if rule, ok := matchTypeRule(cfg, col); ok {
+		return rule.typeIn(cfg.typeMap), rule.Name, "", true, nil
+	}
+	keys := columnTypeKeys(col)
+	if col.IsArray {
+		dims := arrayDimensions(col)
+		shape := strings.Repeat("[]", dims)
+		for _, key := range keys {
+			if typ, ok := mapType(cfg, key+shape, col.Nullable); ok {
+				return typ, typeMapSource(key+shape, col.Nullable), key + shape, true, nil
+			}
+		}
+		if cfg.ArrayType != nil {
+			for _, key := range keys {
+				elem, ok := cfg.TypeMap[key]
+				if !ok {
+					continue
+				}
+				buf := &bytes.Buffer{}
+				d := arrayTypeData{Type: elem, DBType: col.DBType, Dimensions: dims, Nullable: col.Nullable, TypeMap: cfg.typeMap}
+				if err := cfg.ArrayType.Execute(buf, d); err != nil {
+					return "", "", "", false, errors.WithMessage(err, "array type failed for "+col.DBType+shape)
+				}
+				return buf.String(), "ArrayType", key + shape, true, nil
+			}
+		}
+	}
+	for _, key := range keys {
+		if typ, ok := mapType(cfg, key, col.Nullable); ok {
+			return typ, typeMapSource(key, col.Nullable), key, true, nil
+		}
+	}
+	if col.Domain != nil {
+		// fall back to the domain's underlying type.
+		if typ, ok := mapType(cfg, col.Domain.BaseType, col.Nullable); ok {
+			return typ, typeMapSource(col.Domain.BaseType, col.Nullable), col.Domain.BaseType, true, nil
+		}
+	}
+	return "", "", "", false, nil
+}
+
+// arrayDimensions returns the number of dimensions of an array column, at
+// least one, since not every database reports them.
+func arrayDimensions(col *data.Column) int {
+	if col.ArrayDimensions < 1 {
+		return 1
+	}
+	return col.ArrayDimensions
+}
+
+// mapSequenceOwners links the sequences to the table and column owning them,
+// which may be in a different schema than the sequence.
+func mapSequenceOwners(db *data.DBData) {
+	for _, sch := range db.Schemas {
+		for _, seq := range sch.Sequences {
+			if seq.TableDBName == "" {
+				continue
+			}
+			// the owning table may have been filtered out.
+			owner, ok := db.SchemasByName[seq.TableSchemaDBName]
+			if !ok {
+				continue
+			}
+			table, ok := owner.TablesByName[seq.TableDBName]
+			if !ok {
+				continue
+			}
+			seq.Table = table
+			seq.Column = table.ColumnsByName[seq.ColumnDBName]
+			if seq.Column != nil {
+				seq.Column.Sequence = seq
+			}
+		}
+	}
+}
+
+// mapViewSources links the columns of views to the table columns they're
+// read from, and lets the view columns inherit the comment, primary key
+// status and non-nullness of their source, unless the nullability is
+// overridden.  The source columns are only known per view, not per view
+// column, so a view column is matched to a source column with the same name,
+// if exactly one of the view's source columns has that name; aliased columns
+// and names that more than one source has aren't linked.
+func mapViewSources(probs *problems, cfg *Config, info *database.Info, db *data.DBData) error {
+	for _, s := range info.Schemas {
+		sch := db.SchemasByName[s.Name]
+		for _, t := range s.Tables {
+			if len(t.SourceColumns) == 0 {
+				continue
+			}
+			view := sch.TablesByName[t.Name]
+			sources := map[string][]*database.ColumnRef{}
+			for _, ref := range t.SourceColumns {
+				sources[ref.Column] = append(sources[ref.Column], ref)
+			}
+			mapped := map[*data.Column]bool{}
+			for _, col := range view.Columns {
+				refs := sources[col.DBName]
+				if len(refs) != 1 {
+					// either a computed column, or ambiguous.
+					continue
+				}
+				ref := refs[0]
+				refSchema, ok := db.SchemasByName[ref.Schema]
+				if !ok {
+					probs.add("Unmapped source column %v.%v.%v of view %v.%v", ref.Schema, ref.Table, ref.Column, s.Name, t.Name)
+					continue
+				}
+				refTable, ok := refSchema.TablesByName[ref.Table]
+				if !ok {
+					probs.add("Unmapped source column %v.%v.%v of view %v.%v", ref.Schema, ref.Table, ref.Column, s.Name, t.Name)
+					continue
+				}
+				source, ok := refTable.ColumnsByName[ref.Column]
+				if !ok {
+					probs.add("Unmapped source column %v.%v.%v of view %v.%v", ref.Schema, ref.Table, ref.Column, s.Name, t.Name)
+					continue
+				}
+				col.Source = source
+				mapped[source] = true
+				if col.Comment == "" {
+					col.Comment = source.Comment
+					col.Description, col.Annotations = source.Description, source.Annotations
+				}
+				ov := cfg.override(s.Name, t.Name, col.DBName)
+				if col.Nullable && !source.Nullable && ov.Nullable == nil {
+					col.Nullable = false
+					if err := setColumnType(probs, cfg, col, ov); err != nil {
+						return err
+					}
+					if err := setColumnTypes(probs, cfg, col, ov); err != nil {
+						return err
+					}
+				}
+			}
+			// a view column is only a key if the view includes the whole
+			// primary key of the source table.
+		outer:
+			for _, col := range view.Columns {
+				if col.Source == nil || !col.Source.IsPrimaryKey {
+					continue
+				}
+				for _, pk := range col.Source.Table.PrimaryKeys {
+					if !mapped[pk] {
+						continue outer
+					}
+				}
+				col.IsPrimaryKey = true
+			}
+			view.PrimaryKeys = filterPrimaryKeyColumns(view.Columns)
+		}
+	}
+	return nil
+}
+
+// enumNameData is the data passed to the MySQLEnumNaming template.
+type enumNameData struct {
+	Schema string
+	Table  string
+	Column string
+}
+
+// mysqlEnumName returns the DBName of a mysql enum from the MySQLEnumNaming
+// template.
+func mysqlEnumName(cfg *Config, schema string, e *database.Enum) (string, error) {
+	d := enumNameData{Schema: schema, Table: e.Table, Column: e.Name}
+	if len(e.Columns) > 0 {
+		d = enumNameData{Schema: e.Columns[0].Schema, Table: e.Columns[0].Table, Column: e.Columns[0].Column}
+	}
+	buf := &bytes.Buffer{}
+	if err := cfg.MySQLEnumNaming.Execute(buf, d); err != nil {
+		return "", errors.WithMessage(err, "enum naming failed for "+d.Table+"."+d.Column)
+	}
+	return buf.String(), nil
+}
+
+// mergeEnums merges mysql enums that have identical values into the first of
+// them, which gets the columns of all of them.  Postgres enums are named types
+// and are never merged.
+func mergeEnums(enums []*database.Enum) []*database.Enum {
+	var out []*database.Enum
+	byValues := map[string]*database.Enum{}
+	for _, e := range enums {
+		if e.Table == "" {
+			out = append(out, e)
+			continue
+		}
+		key := fmt.Sprint(e.IsSet)
+		for _, v := range e.Values {
+			key += "\x00" + v.Name
+		}
+		if merged, ok := byValues[key]; ok {
+			merged.Columns = append(merged.Columns, e.Columns...)
+			continue
+		}
+		merged := *e
+		merged.Columns = append([]*database.ColumnRef(nil), e.Columns...)
+		byValues[key] = &merged
+		out = append(out, &merged)
+	}
+	return out
+}
+
+// mapEnumColumns links a mysql enum to its table and the columns that use it,
+// except the columns excluded by an override.
+func mapEnumColumns(probs *problems, cfg *Config, e *database.Enum, enum *data.Enum, sch *data.Schema) {
+	if table, ok := sch.TablesByName[e.Table]; ok {
+		enum.Table = table
+	}
+	for _, ref := range e.Columns {
+		if cfg.override(sch.DBName, ref.Table, ref.Column).Exclude {
+			continue
+		}
+		table, ok := sch.TablesByName[ref.Table]
+		if !ok {
+			probs.add("Unmapped table %v for enum %v in %v", ref.Table, enum.DBName, sch.DBName)
+			continue
+		}
+		col, ok := table.ColumnsByName[ref.Column]
+		if !ok {
+			probs.add("Unmapped column %v.%v for enum %v in %v", ref.Table, ref.Column, enum.DBName, sch.DBName)
+			continue
+		}
+		col.Enum = enum
+		enum.Columns = append(enum.Columns, col)
+	}
+}
+
+// mapParentTables links partitions and inherited tables to their parent
+// tables, which may be in a different schema.
+func mapParentTables(probs *problems, info *database.Info, db *data.DBData) {
+	for _, s := range info.Schemas {
+		sch := db.SchemasByName[s.Name]
+		for _, t := range s.Tables {
+			if t.Parent == "" {
+				continue
+			}
+			table := sch.TablesByName[t.Name]
+			parentSchema, ok := db.SchemasByName[t.ParentSchema]
+			if !ok {
+				probs.add("Unmapped parent table %v.%v of %v.%v", t.ParentSchema, t.Parent, s.Name, t.Name)
+				continue
+			}
+			parent, ok := parentSchema.TablesByName[t.Parent]
+			if !ok {
+				probs.add("Unmapped parent table %v.%v of %v.%v", t.ParentSchema, t.Parent, s.Name, t.Name)
+				continue
+			}
+			table.Parent = parent
+			parent.Children = append(parent.Children, table)
+		}
+	}
+}
+
+// columnTypeKeys returns the keys the column's type may be mapped with, from
+// the most to the least specific, e.g. "int(10) unsigned", "int unsigned",
+// "int", or "billing.currency", "currency".
+func columnTypeKeys(col *data.Column) []string {
+	var keys []string
+	if col.TypeSchema != "" {
+		keys = append(keys, col.TypeSchema+"."+col.DBType)
+	}
+	if col.ColumnType != "" && col.ColumnType != col.DBType {
+		keys = append(keys, col.ColumnType)
+	}
+	if col.Zerofill {
+		keys = append(keys, col.DBType+" unsigned zerofill")
+	}
+	if col.Unsigned {
+		keys = append(keys, col.DBType+" unsigned")
+	}
+	return append(keys, col.DBType)
+}
+
+// typeMapSource returns the TypeSource of a column whose type was mapped with
+// the given key of the NullableTypeMap if nullable is true, otherwise of the
+// TypeMap.
+func typeMapSource(key string, nullable bool) string {
+	if nullable {
+		return fmt.Sprintf("NullableTypeMap[%q]", key)
+	}
+	return fmt.Sprintf("TypeMap[%q]", key)
+}
+
+// mapType returns the replacement for the given database type from the
+// NullableTypeMap if nullable is true, otherwise from the TypeMap.
+func mapType(cfg *Config, dbType string, nullable bool) (string, bool) {
+	if nullable {
+		typ, ok := cfg.NullableTypeMap[dbType]
+		return typ, ok
+	}
+	typ, ok := cfg.TypeMap[dbType]
+	return typ, ok
+}
+
 func filterPrimaryKeyColumns(columns data.Columns) data.Columns {
 	var pkColumns data.Columns
 	for _, column := range columns {
@@ -155,32 +759,68 @@ func filterPrimaryKeyColumns(columns data.Columns) data.Columns {
 	return pkColumns
 }
 
-func mapSchemaForeignKeyReferences(isch *database.Schema, sch *data.Schema, convert nameConverter) error {
+func mapSchemaForeignKeyReferences(probs *problems, cfg *Config, isch *database.Schema, db *data.DBData, convert nameConverter) error {
+	sch := db.SchemasByName[isch.Name]
 	for _, t := range isch.Tables {
 		table, ok := sch.TablesByName[t.Name]
 		if !ok {
-			log.Printf("Unmapped table %v in %v", t.Name, isch.Name)
+			probs.add("Unmapped table %v in %v", t.Name, isch.Name)
 			continue
 		}
 
+		// a foreign key is left out as a whole if any of its columns, or any of
+		// the columns it references, is excluded.
+		excludedFKs := map[string]bool{}
+		for _, c := range t.Columns {
+			if !c.IsForeignKey || c.ForeignKey == nil {
+				continue
+			}
+			refSchemaName := c.ForeignKey.ForeignSchemaName
+			if refSchemaName == "" {
+				refSchemaName = isch.Name
+			}
+			if cfg.override(isch.Name, t.Name, c.Name).Exclude || cfg.override(refSchemaName, c.ForeignKey.ForeignTableName, c.ForeignKey.ForeignColumnName).Exclude {
+				excludedFKs[c.ForeignKey.Name] = true
+			}
+		}
+
 		fkColumnsByFKNames := map[string]data.ForeignKeyColumns{}
+		// the foreign keys in the order of their first column, so the table's
+		// foreign keys are in a stable order.
+		var fkNames []string
+		dbfks := map[string]*database.ForeignKey{}
 
 		for _, c := range t.Columns {
+			if cfg.override(isch.Name, t.Name, c.Name).Exclude {
+				continue
+			}
 			column, ok := table.ColumnsByName[c.Name]
 			if !ok {
-				log.Printf("Unmapped column %v in %v.%v", c.Name, isch.Name, t.Name)
+				probs.add("Unmapped column %v in %v.%v", c.Name, isch.Name, t.Name)
 				continue
 			}
 
+			if column.IsFK && excludedFKs[c.ForeignKey.Name] {
+				column.IsFK = false
+			}
 			if column.IsFK {
-				refTable, ok := sch.TablesByName[c.ForeignKey.ForeignTableName]
+				refSchemaName := c.ForeignKey.ForeignSchemaName
+				if refSchemaName == "" {
+					refSchemaName = isch.Name
+				}
+				refSchema, ok := db.SchemasByName[refSchemaName]
 				if !ok {
-					log.Printf("Unmapped foreign table %v in %v", c.ForeignKey.ForeignTableName, isch.Name)
+					probs.add("Unmapped foreign schema %v for foreign key %v of column %v.%v.%v", refSchemaName, c.ForeignKey.Name, isch.Name, t.Name, c.Name)
+					continue
+				}
+				refTable, ok := refSchema.TablesByName[c.ForeignKey.ForeignTableName]
+				if !ok {
+					probs.add("Unmapped foreign table %v.%v for foreign key %v of column %v.%v.%v", refSchemaName, c.ForeignKey.ForeignTableName, c.ForeignKey.Name, isch.Name, t.Name, c.Name)
 					continue
 				}
 				refColumn, ok := refTable.ColumnsByName[c.ForeignKey.ForeignColumnName]
 				if !ok {
-					log.Printf("Unmapped foreign column %v in %v.%v", c.ForeignKey.ForeignColumnName, isch.Name, c.ForeignKey.ForeignTableName)
+					probs.add("Unmapped foreign column %v.%v.%v for foreign key %v of column %v.%v.%v", refSchemaName, c.ForeignKey.ForeignTableName, c.ForeignKey.ForeignColumnName, c.ForeignKey.Name, isch.Name, t.Name, c.Name)
 					continue
 				}
 
@@ -199,14 +839,16 @@ func mapSchemaForeignKeyReferences(isch *database.Schema, sch *data.Schema, conv
 
 				if _, ok := fkColumnsByFKNames[fkColumn.DBName]; !ok {
 					fkColumnsByFKNames[fkColumn.DBName] = data.ForeignKeyColumns{fkColumn}
+					fkNames = append(fkNames, fkColumn.DBName)
+					dbfks[fkColumn.DBName] = c.ForeignKey
 				} else {
 					fkColumnsByFKNames[fkColumn.DBName] = append(fkColumnsByFKNames[fkColumn.DBName], fkColumn)
 				}
 			}
 		}
 
-		for _, fkc := range fkColumnsByFKNames {
-			err := mapForeignTable(fkc, convert)
+		for _, name := range fkNames {
+			err := mapForeignTable(cfg, fkColumnsByFKNames[name], dbfks[name], convert)
 			if err != nil {
 				return err
 			}
@@ -216,7 +858,7 @@ func mapSchemaForeignKeyReferences(isch *database.Schema, sch *data.Schema, conv
 	return nil
 }
 
-func mapForeignTable(fkc data.ForeignKeyColumns, convert nameConverter) error {
+func mapForeignTable(cfg *Config, fkc data.ForeignKeyColumns, dbfk *database.ForeignKey, convert nameConverter) error {
 	if len(fkc) == 0 {
 		return nil
 	}
@@ -224,21 +866,30 @@ func mapForeignTable(fkc data.ForeignKeyColumns, convert nameConverter) error {
 	// All ForeignKeyColumns will point to same table/refTable and have the same name, use first one
 	table := fkc[0].Column.Table
 	refTable := fkc[0].RefColumn.Table
-	cName, err := convert(fkc[0].DBName)
+	fk := &data.ForeignKey{
+		DBName:            fkc[0].DBName,
+		TableDBName:       table.DBName,
+		RefSchemaDBName:   refTable.Schema.DBName,
+		RefTableDBName:    refTable.DBName,
+		Table:             table,
+		RefTable:          refTable,
+		FKColumns:         fkc,
+		IsDeferrable:      dbfk.IsDeferrable,
+		InitiallyDeferred: dbfk.InitiallyDeferred,
+		Comment:           dbfk.Comment,
+	}
+	fk.Description, fk.Annotations = parseAnnotations(cfg.AnnotationPattern, dbfk.Comment)
+	for _, c := range fkc {
+		if c.Column.Nullable {
+			fk.Nullable = true
+		}
+	}
+	var err error
+	fk.Name, err = convert(cfg.ForeignKeyNameConversion, nameData{DBName: fk.DBName, Schema: table.Schema, Table: table, ForeignKey: fk})
 	if err != nil {
 		return errors.Wrap(err, "foreign key")
 	}
 
-	fk := &data.ForeignKey{
-		DBName:         fkc[0].DBName,
-		Name:           cName,
-		TableDBName:    table.DBName,
-		RefTableDBName: refTable.DBName,
-		Table:          table,
-		RefTable:       refTable,
-		FKColumns:      fkc,
-	}
-
 	table.ForeignKeys = append(table.ForeignKeys, fk)
 	refTable.ForeignKeyRefs = append(refTable.ForeignKeyRefs, fk)
 	table.FKByName[fk.DBName] = fk
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/google/go-cmp/cmp"
 	"gnorm.org/gnorm/database"
 	"gnorm.org/gnorm/environ"
 	"gnorm.org/gnorm/run/data"
@@ -56,7 +57,7 @@ func TestMakeData(t *testing.T) {
 
 	buf := &bytes.Buffer{}
 	l := log.New(buf, "", 0)
-	data, err := makeData(l, info, c)
+	data, err := makeData(environ.Values{Log: l}, info, c)
 	if err != nil {
 		t.Fatal("unexpected error from convertNames", err)
 	}
@@ -206,7 +207,7 @@ func TestForeignKeyRefs(t *testing.T) {
 	}
 
 	log := log.New(&bytes.Buffer{}, "", 0)
-	data, err := makeData(log, info, c)
+	data, err := makeData(environ.Values{Log: log}, info, c)
 	if err != nil {
 		t.Fatalf("unexpected err: %s", err)
 	}
@@ -286,3 +287,758 @@ func TestForeignKeyRefs(t *testing.T) {
 		t.Fatalf("incorrect foreign key ref column; expected %s, got %s", "col_3", name)
 	}
 }
+
+func TestMakeDataDomains(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{
+				"text":           "string",
+				"positive_money": "Money",
+				"integer":        "int",
+			},
+			NullableTypeMap: map[string]string{
+				"text": "sql.NullString",
+			},
+		},
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Domains: []*database.Domain{{
+				Name:     "email",
+				BaseType: "text",
+				Nullable: true,
+				Constraints: []*database.DomainConstraint{{
+					Name:       "email_check",
+					Definition: "CHECK (VALUE ~~ '%@%'::text)",
+				}},
+			}, {
+				Name:     "positive_money",
+				BaseType: "numeric",
+			}},
+			CompositeTypes: []*database.CompositeType{{
+				Name: "address",
+				Attributes: []*database.CompositeAttribute{{
+					Name:     "street",
+					Type:     "text",
+					Nullable: true,
+					Ordinal:  1,
+				}, {
+					Name:    "number",
+					Type:    "integer",
+					Ordinal: 2,
+				}},
+			}},
+			Tables: []*database.Table{{
+				Name: "accounts",
+				Columns: []*database.Column{{
+					Name:        "email",
+					Type:        "email",
+					Domain:      "email",
+					UserDefined: true,
+					Nullable:    true,
+				}, {
+					Name:        "balance",
+					Type:        "positive_money",
+					Domain:      "positive_money",
+					UserDefined: true,
+					Nullable:    true,
+				}, {
+					Name:        "address",
+					Type:        "address",
+					UserDefined: true,
+				}},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.Schemas[0]
+	if l := len(sch.Domains); l != 2 {
+		t.Fatalf("incorrect number of domains; expected %d, got %d", 2, l)
+	}
+	email := sch.DomainsByName["email"]
+	if email == nil {
+		t.Fatal("email domain not found by name")
+	}
+	if l := len(email.Constraints); l != 1 {
+		t.Fatalf("incorrect number of domain constraints; expected %d, got %d", 1, l)
+	}
+	if typ := email.Type; typ != "sql.NullString" {
+		t.Errorf("incorrect domain type; expected %q, got %q", "sql.NullString", typ)
+	}
+
+	table := sch.TablesByName["accounts"]
+	col := table.ColumnsByName["email"]
+	if col.Domain != email {
+		t.Error("email column not linked to its domain")
+	}
+	if col.Type != "sql.NullString" {
+		t.Errorf("unmapped domain should fall back to its base type; expected %q, got %q", "sql.NullString", col.Type)
+	}
+
+	col = table.ColumnsByName["balance"]
+	if col.Nullable {
+		t.Error("column with a NOT NULL domain should not be nullable")
+	}
+	if col.Type != "Money" {
+		t.Errorf("mapped domain should use its own mapping; expected %q, got %q", "Money", col.Type)
+	}
+
+	col = table.ColumnsByName["address"]
+	if col.CompositeType == nil || col.CompositeType.DBName != "address" {
+		t.Fatal("address column not linked to its composite type")
+	}
+	attrs := col.CompositeType.Attributes
+	if l := len(attrs); l != 2 {
+		t.Fatalf("incorrect number of composite attributes; expected %d, got %d", 2, l)
+	}
+	if attrs[0].Type != "sql.NullString" || attrs[1].Type != "int" {
+		t.Errorf("incorrect composite attribute types; got %q and %q", attrs[0].Type, attrs[1].Type)
+	}
+}
+
+func TestMakeDataParentTables(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name:         "measurements",
+				Kind:         database.KindPartitioned,
+				PartitionKey: "RANGE (logdate)",
+			}, {
+				Name:         "measurements_archive",
+				Kind:         database.KindPartition,
+				ParentSchema: "public",
+				Parent:       "measurements",
+			}},
+		}, {
+			Name: "archive",
+			Tables: []*database.Table{{
+				Name:           "measurements_2017",
+				Kind:           database.KindPartition,
+				PartitionBound: "FOR VALUES FROM ('2017-01-01') TO ('2018-01-01')",
+				ParentSchema:   "public",
+				Parent:         "measurements",
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	parent := db.SchemasByName["public"].TablesByName["measurements"]
+	if parent.Kind != database.KindPartitioned {
+		t.Errorf("incorrect table kind; expected %q, got %q", database.KindPartitioned, parent.Kind)
+	}
+	if l := len(parent.Children); l != 2 {
+		t.Fatalf("incorrect number of child tables; expected %d, got %d", 2, l)
+	}
+	child := db.SchemasByName["archive"].TablesByName["measurements_2017"]
+	if child.Parent != parent {
+		t.Error("partition in another schema not linked to its parent")
+	}
+}
+
+func TestMakeDataSequencesAndTriggers(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{{
+					Name:       "id",
+					Type:       "integer",
+					HasDefault: true,
+				}},
+				Triggers: []*database.Trigger{{
+					Name:       "users_audit",
+					Timing:     "AFTER",
+					Events:     []string{"INSERT", "UPDATE"},
+					ForEachRow: true,
+					Function:   "public.audit",
+				}},
+			}},
+			Sequences: []*database.Sequence{{
+				Name:        "users_id_seq",
+				Type:        "integer",
+				Start:       1,
+				Increment:   1,
+				Min:         1,
+				Max:         2147483647,
+				OwnerTable:  "users",
+				OwnerColumn: "id",
+			}, {
+				Name:      "invoice_numbers",
+				Type:      "bigint",
+				Start:     1000,
+				Increment: 1,
+			}, {
+				Name:        "events_id_seq",
+				Type:        "integer",
+				Start:       1,
+				Increment:   1,
+				OwnerSchema: "archive",
+				OwnerTable:  "events",
+				OwnerColumn: "id",
+			}},
+		}, {
+			Name: "archive",
+			Tables: []*database.Table{{
+				Name:    "events",
+				Columns: []*database.Column{{Name: "id", Type: "integer", HasDefault: true}},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	table := sch.TablesByName["users"]
+	if !table.HasTriggers() {
+		t.Fatal("expected table to have triggers")
+	}
+	if tr := table.Triggers[0]; tr.Table != table || tr.Timing != "AFTER" || len(tr.Events) != 2 {
+		t.Errorf("incorrect trigger: %#v", tr)
+	}
+
+	seq := sch.SequencesByName["users_id_seq"]
+	col := table.ColumnsByName["id"]
+	if seq.Column != col || col.Sequence != seq {
+		t.Error("sequence not linked to its owning column")
+	}
+	if seq := sch.SequencesByName["invoice_numbers"]; seq.Table != nil || seq.Column != nil {
+		t.Error("unowned sequence should not be linked to a table")
+	}
+	events := db.SchemasByName["archive"].TablesByName["events"]
+	if seq := sch.SequencesByName["events_id_seq"]; seq.Table != events || seq.TableSchemaDBName != "archive" || events.ColumnsByName["id"].Sequence != seq {
+		t.Error("sequence not linked to its owning column in another schema")
+	}
+}
+
+func TestMakeDataViewSources(t *testing.T) {
+	t.Parallel()
+
+	nullable := true
+	c := &Config{
+		ConfigData: data.ConfigData{
+			TypeMap:         map[string]string{"integer": "int", "text": "string"},
+			NullableTypeMap: map[string]string{"integer": "sql.NullInt64", "text": "sql.NullString"},
+		},
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		Overrides: map[OverrideKey]Override{
+			// the view can't tell name comes from the outer side of a join.
+			{Schema: "public", Table: "post_authors", Column: "name"}: {Nullable: &nullable},
+		},
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{{
+					Name:         "id",
+					Type:         "integer",
+					IsPrimaryKey: true,
+					Comment:      "the user id",
+				}, {
+					Name: "name",
+					Type: "text",
+				}},
+			}, {
+				Name: "posts",
+				Columns: []*database.Column{{
+					Name:         "post_id",
+					Type:         "integer",
+					IsPrimaryKey: true,
+				}, {
+					Name:     "author_id",
+					Type:     "integer",
+					Nullable: true,
+				}, {
+					Name:    "title",
+					Type:    "text",
+					Comment: "the title",
+				}},
+			}, {
+				Name:           "user_names",
+				IsView:         true,
+				ViewDefinition: "SELECT u.id, u.name, upper(u.name) AS shout, u.id AS user_id FROM users u",
+				SourceColumns: []*database.ColumnRef{
+					{Schema: "public", Table: "users", Column: "id"},
+					{Schema: "public", Table: "users", Column: "name"},
+				},
+				Columns: []*database.Column{
+					{Name: "id", Type: "integer", Nullable: true, Comment: "the id"},
+					{Name: "name", Type: "text", Nullable: true},
+					{Name: "shout", Type: "text", Nullable: true},
+					{Name: "user_id", Type: "integer", Nullable: true},
+				},
+			}, {
+				Name:           "post_authors",
+				IsView:         true,
+				ViewDefinition: "SELECT p.post_id, p.title, u.name FROM posts p LEFT JOIN users u ON u.id = p.author_id",
+				SourceColumns: []*database.ColumnRef{
+					{Schema: "public", Table: "posts", Column: "post_id"},
+					{Schema: "public", Table: "posts", Column: "title"},
+					{Schema: "public", Table: "posts", Column: "author_id"},
+					{Schema: "public", Table: "users", Column: "id"},
+					{Schema: "public", Table: "users", Column: "name"},
+				},
+				Columns: []*database.Column{
+					{Name: "post_id", Type: "integer", Nullable: true},
+					{Name: "title", Type: "text", Nullable: true},
+					{Name: "name", Type: "text", Nullable: true},
+				},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	users, posts := sch.TablesByName["users"], sch.TablesByName["posts"]
+	userNames := sch.TablesByName["user_names"]
+	if userNames.ViewDefinition != info.Schemas[0].Tables[2].ViewDefinition {
+		t.Errorf("view definition not copied, got %q", userNames.ViewDefinition)
+	}
+
+	id := userNames.ColumnsByName["id"]
+	if id.Source != users.ColumnsByName["id"] {
+		t.Fatal("view column not linked to its source column")
+	}
+	if id.Nullable || id.Type != "int" || !id.IsPrimaryKey || id.Comment != "the id" {
+		t.Errorf("expected a view column to inherit non-nullness and the key, but keep its own comment, got nullable %v, type %q, key %v, comment %q", id.Nullable, id.Type, id.IsPrimaryKey, id.Comment)
+	}
+	if got := userNames.PrimaryKeys.DBNames(); len(got) != 1 || got[0] != "id" {
+		t.Errorf("expected the view to have the primary key of its source, got %v", got)
+	}
+	if col := userNames.ColumnsByName["shout"]; col.Source != nil || !col.Nullable || col.Type != "sql.NullString" {
+		t.Error("computed view column should not have a source")
+	}
+	if col := userNames.ColumnsByName["user_id"]; col.Source != nil || !col.Nullable || col.IsPrimaryKey {
+		t.Error("aliased view column should not have a source")
+	}
+
+	postAuthors := sch.TablesByName["post_authors"]
+	postID := postAuthors.ColumnsByName["post_id"]
+	if postID.Source != posts.ColumnsByName["post_id"] || postID.Nullable || !postID.IsPrimaryKey {
+		t.Errorf("expected post_id to be linked and inherit the key, got nullable %v, key %v", postID.Nullable, postID.IsPrimaryKey)
+	}
+	if col := postAuthors.ColumnsByName["title"]; col.Source != posts.ColumnsByName["title"] || col.Nullable || col.Type != "string" || col.Comment != "the title" {
+		t.Error("view column not linked to its source column in the joined table")
+	}
+	name := postAuthors.ColumnsByName["name"]
+	if name.Source != users.ColumnsByName["name"] {
+		t.Fatal("view column not linked to its source column")
+	}
+	if !name.Nullable || name.Type != "sql.NullString" {
+		t.Errorf("expected the overridden nullability to win over the source's, got nullable %v type %q", name.Nullable, name.Type)
+	}
+	if users.ColumnsByName["id"].Comment != "the user id" {
+		t.Error("source column comment changed")
+	}
+}
+
+func TestMakeDataColumnTypeKeys(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{
+				"int":           "int32",
+				"int unsigned":  "uint32",
+				"numeric":       "float64",
+				"numeric(12,2)": "decimal.Money",
+			},
+		},
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "schema",
+			Tables: []*database.Table{{
+				Name: "table",
+				Columns: []*database.Column{
+					{Name: "signed", Type: "int", ColumnType: "int(11)"},
+					{Name: "unsigned", Type: "int", ColumnType: "int(10) unsigned", Unsigned: true},
+					{Name: "zerofill", Type: "int", ColumnType: "int(10) unsigned zerofill", Unsigned: true, Zerofill: true},
+					{Name: "price", Type: "numeric", ColumnType: "numeric(12,2)", Precision: 12, Scale: 2},
+					{Name: "ratio", Type: "numeric", ColumnType: "numeric(5,4)", Precision: 5, Scale: 4},
+					{Name: "amount", Type: "numeric"},
+				},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	expected := map[string]string{
+		"signed":   "int32",
+		"unsigned": "uint32",
+		"zerofill": "uint32",
+		"price":    "decimal.Money",
+		"ratio":    "float64",
+		"amount":   "float64",
+	}
+	cols := db.SchemasByName["schema"].TablesByName["table"].ColumnsByName
+	for name, typ := range expected {
+		if cols[name].Type != typ {
+			t.Errorf("column %q: expected type %q, got %q", name, typ, cols[name].Type)
+		}
+	}
+}
+
+func TestMakeDataMySQLEnums(t *testing.T) {
+	t.Parallel()
+
+	status := func(table string) *database.Enum {
+		return &database.Enum{
+			Table: table,
+			Name:  "status",
+			Values: []*database.EnumValue{
+				{Name: "active", Value: 1},
+				{Name: "disabled", Value: 2},
+			},
+			Columns: []*database.ColumnRef{{Schema: "shop", Table: table, Column: "status"}},
+		}
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "shop",
+			Tables: []*database.Table{{
+				Name:    "users",
+				Columns: []*database.Column{{Name: "status", Type: "enum"}},
+			}, {
+				Name:    "products",
+				Columns: []*database.Column{{Name: "status", Type: "enum"}},
+			}},
+			Enums: []*database.Enum{status("users"), status("products")},
+		}},
+	}
+
+	c := &Config{
+		NameConversion:  template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{pascal .}}`)),
+		MySQLEnumNaming: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.Table}}_{{.Column}}`)),
+	}
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["shop"]
+	if diff := cmp.Diff(sch.Enums.DBNames(), data.Strings{"users_status", "products_status"}); diff != "" {
+		t.Errorf("wrong enum names:\n%s", diff)
+	}
+	if sch.Enums[0].Name != "UsersStatus" {
+		t.Errorf("expected converted name %q, got %q", "UsersStatus", sch.Enums[0].Name)
+	}
+	users := sch.TablesByName["users"]
+	if col := users.ColumnsByName["status"]; col.Enum != sch.Enums[0] || sch.Enums[0].Table != users {
+		t.Error("enum not linked to its column and table")
+	}
+
+	c.MergeMySQLEnums = true
+	db, err = makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch = db.SchemasByName["shop"]
+	if len(sch.Enums) != 1 {
+		t.Fatalf("expected enums to be merged into 1, got %v", sch.Enums.DBNames())
+	}
+	enum := sch.Enums[0]
+	if enum.DBName != "users_status" {
+		t.Errorf("expected merged enum to be named after its first column, got %q", enum.DBName)
+	}
+	if len(enum.Columns) != 2 {
+		t.Fatalf("expected merged enum to have 2 columns, got %d", len(enum.Columns))
+	}
+	if sch.TablesByName["products"].ColumnsByName["status"].Enum != enum {
+		t.Error("column not linked to merged enum")
+	}
+	if len(info.Schemas[0].Enums[0].Columns) != 1 {
+		t.Error("merging enums modified the database info")
+	}
+}
+
+func TestMakeDataCrossSchemaForeignKeys(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "billing",
+			Tables: []*database.Table{{
+				Name: "invoices",
+				Columns: []*database.Column{{
+					Name:         "id",
+					Type:         "integer",
+					IsPrimaryKey: true,
+				}, {
+					Name:         "user_id",
+					Type:         "integer",
+					IsForeignKey: true,
+					ForeignKey: &database.ForeignKey{
+						SchemaName:        "billing",
+						TableName:         "invoices",
+						ColumnName:        "user_id",
+						Name:              "invoices_user_id_fkey",
+						ForeignSchemaName: "auth",
+						ForeignTableName:  "users",
+						ForeignColumnName: "id",
+					},
+				}},
+			}},
+		}, {
+			Name: "auth",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{{
+					Name:         "id",
+					Type:         "integer",
+					IsPrimaryKey: true,
+				}},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	invoices := db.SchemasByName["billing"].TablesByName["invoices"]
+	users := db.SchemasByName["auth"].TablesByName["users"]
+	fk, ok := invoices.FKByName["invoices_user_id_fkey"]
+	if !ok {
+		t.Fatal("foreign key to a table in another schema was not mapped")
+	}
+	if fk.RefTable != users {
+		t.Error("foreign key references the wrong table")
+	}
+	if fk.RefSchemaDBName != "auth" {
+		t.Errorf("expected RefSchemaDBName %q, got %q", "auth", fk.RefSchemaDBName)
+	}
+	if users.FKRefsByName["invoices_user_id_fkey"] != fk {
+		t.Error("referenced table in another schema doesn't list the foreign key")
+	}
+	if !users.ColumnsByName["id"].HasFKRef {
+		t.Error("referenced column in another schema not marked HasFKRef")
+	}
+}
+
+func TestMakeDataCrossSchemaEnums(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{
+				"currency":         "string",
+				"billing.currency": "billing.Currency",
+			},
+		},
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+
+	currency := func() *database.Enum {
+		return &database.Enum{
+			Name:   "currency",
+			Values: []*database.EnumValue{{Name: "usd", Value: 1}, {Name: "eur", Value: 2}},
+		}
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "payments",
+				Columns: []*database.Column{{
+					Name:        "billing_currency",
+					Type:        "currency",
+					UserDefined: true,
+					TypeSchema:  "billing",
+				}, {
+					Name:        "public_currency",
+					Type:        "currency",
+					UserDefined: true,
+					TypeSchema:  "public",
+				}, {
+					Name:        "currencies",
+					Type:        "currency",
+					IsArray:     true,
+					UserDefined: true,
+					TypeSchema:  "billing",
+				}},
+			}},
+			Enums: []*database.Enum{currency()},
+		}, {
+			Name:  "billing",
+			Enums: []*database.Enum{currency()},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	billing := db.SchemasByName["billing"].EnumsByName["currency"]
+	public := db.SchemasByName["public"].EnumsByName["currency"]
+	cols := db.SchemasByName["public"].TablesByName["payments"].ColumnsByName
+
+	if cols["billing_currency"].Enum != billing {
+		t.Error("column not linked to the enum in the other schema")
+	}
+	if cols["public_currency"].Enum != public {
+		t.Error("column not linked to the enum in its own schema")
+	}
+	if diff := cmp.Diff(billing.Columns.DBNames(), data.Strings{"billing_currency", "currencies"}); diff != "" {
+		t.Errorf("wrong enum columns:\n%s", diff)
+	}
+	if typ := cols["billing_currency"].Type; typ != "billing.Currency" {
+		t.Errorf("expected schema-qualified type map key to be used, got %q", typ)
+	}
+	if typ := cols["public_currency"].Type; typ != "string" {
+		t.Errorf("expected unqualified type map key to be used, got %q", typ)
+	}
+}
+
+func TestMakeDataArrayTypes(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{
+				"int4":   "int32",
+				"text":   "string",
+				"text[]": "pq.StringArray",
+			},
+		},
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ArrayType:      template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{repeat "[]" .Dimensions}}{{.Type}}`)),
+	}
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "schema",
+			Tables: []*database.Table{{
+				Name: "table",
+				Columns: []*database.Column{
+					{Name: "scalar", Type: "int4"},
+					{Name: "ints", Type: "int4", IsArray: true, ArrayDimensions: 1},
+					{Name: "grid", Type: "int4", IsArray: true, ArrayDimensions: 2},
+					{Name: "undeclared", Type: "int4", IsArray: true},
+					{Name: "texts", Type: "text", IsArray: true, ArrayDimensions: 1},
+				},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	expected := map[string]string{
+		"scalar":     "int32",
+		"ints":       "[]int32",
+		"grid":       "[][]int32",
+		"undeclared": "[]int32",
+		"texts":      "pq.StringArray",
+	}
+	cols := db.SchemasByName["schema"].TablesByName["table"].ColumnsByName
+	for name, typ := range expected {
+		if cols[name].Type != typ {
+			t.Errorf("column %q: expected type %q, got %q", name, typ, cols[name].Type)
+		}
+	}
+}
+
+func TestMakeDataNameConversions(t *testing.T) {
+	t.Parallel()
+
+	parse := func(s string) *template.Template {
+		return template.Must(template.New("").Funcs(environ.FuncMap).Parse(s))
+	}
+	c := &Config{
+		NameConversion:           parse(`{{pascal .}}`),
+		TableNameConversion:      parse(`{{pascal (singular .DBName)}}`),
+		ColumnNameConversion:     parse(`{{.Table.Name}}{{pascal .DBName}}`),
+		EnumValueNameConversion:  parse(`{{.Enum.Name}}{{pascal .DBName}}`),
+		IndexNameConversion:      parse(`{{.Table.Name}}Index`),
+		ForeignKeyNameConversion: parse(`{{.ForeignKey.RefTable.Name}}Key`),
+		RelationNameConversion:   parse(`{{if eq .Relation.Kind "belongs-to"}}{{pascal .DBName}}{{else}}{{pascal (plural .DBName)}}{{end}}`),
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Enums: []*database.Enum{{
+				Name:   "book_type",
+				Values: []*database.EnumValue{{Name: "paperback"}},
+			}},
+			Tables: []*database.Table{{
+				Name:    "authors",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name:    "books",
+				Columns: []*database.Column{idColumn(), fkColumn("author_id", "books", "authors")},
+				Indexes: []*database.Index{{Name: "books_pkey", IsUnique: true, Columns: []*database.Column{{Name: "id"}}}},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	books := sch.TablesByName["books"]
+	authors := sch.TablesByName["authors"]
+	tests := []struct {
+		what     string
+		got      string
+		expected string
+	}{
+		{"schema", sch.Name, "Public"},
+		{"table", books.Name, "Book"},
+		{"column", books.ColumnsByName["author_id"].Name, "BookAuthorID"},
+		{"enum", sch.EnumsByName["book_type"].Name, "BookType"},
+		{"enum value", sch.EnumsByName["book_type"].Values[0].Name, "BookTypePaperback"},
+		{"index", books.Indexes[0].Name, "BookIndex"},
+		{"foreign key", books.ForeignKeys[0].Name, "AuthorKey"},
+		{"belongs-to relation", books.Relations[0].Name, "Author"},
+		{"has-many relation", authors.Relations[0].Name, "Books"},
+	}
+	for _, test := range tests {
+		if test.got != test.expected {
+			t.Errorf("%s: expected name %q, got %q", test.what, test.expected, test.got)
+		}
+	}
+}
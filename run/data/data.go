@@ -10,8 +10,12 @@ import (
 
 // DBData is all the data about a database that we know.
 type DBData struct {
-	Schemas       []*Schema
-	SchemasByName map[string]*Schema `yaml:"-" json:"-"` // dbname to schema
+	Schemas             []*Schema
+	SchemasByName       map[string]*Schema `yaml:"-" json:"-"` // dbname to schema
+	OrderedTables       Tables             `yaml:"-" json:"-"` // the tables of all schemas in foreign key dependency order
+	OrderedTableDBNames Strings            // the original names of the tables in OrderedTables, in the same order
+	Cycles              []*Cycle           // the groups of tables whose foreign keys reference each other in a loop, not counting tables that only reference themselves
+	BrokenForeignKeys   ForeignKeys        // the foreign keys ignored to put the tables of cycles in order
 }
 
 // SchemaData is the data passed to schema templates.
@@ -40,11 +44,22 @@ type EnumData struct {
 
 // Schema is the data about a DB schema.
 type Schema struct {
-	Name         string            // the converted name of the schema
-	DBName       string            // the original name of the schema in the DB
-	Tables       Tables            // the list of tables in this schema
-	Enums        Enums             // the list of enums in this schema
-	TablesByName map[string]*Table `yaml:"-" json:"-"` // dbnames to tables
+	Name                 string                    // the converted name of the schema
+	DBName               string                    // the original name of the schema in the DB
+	Tables               Tables                    // the list of tables in this schema
+	Enums                Enums                     // the list of enums in this schema
+	CompositeTypes       CompositeTypes            // (postgres) the list of composite types in this schema
+	Domains              Domains                   // (postgres) the list of domains in this schema
+	Sequences            Sequences                 // (postgres) the list of sequences in this schema
+	TablesByName         map[string]*Table         `yaml:"-" json:"-"` // dbnames to tables
+	EnumsByName          map[string]*Enum          `yaml:"-" json:"-"` // dbnames to enums
+	CompositeTypesByName map[string]*CompositeType `yaml:"-" json:"-"` // dbnames to composite types
+	DomainsByName        map[string]*Domain        `yaml:"-" json:"-"` // dbnames to domains
+	SequencesByName      map[string]*Sequence      `yaml:"-" json:"-"` // dbnames to sequences
+	OrderedTables        Tables                    `yaml:"-" json:"-"` // the tables in this schema in foreign key dependency order
+	OrderedTableDBNames  Strings                   // the original names of the tables in OrderedTables, in the same order
+	Cycles               []*Cycle                  `yaml:"-" json:"-"` // the cycles that include tables in this schema
+	Imports              Imports                   // the imports needed by the types of the columns, domains and composite types in this schema
 }
 
 // Table is the data about a DB Table.
@@ -52,19 +67,32 @@ type Table struct {
 	Name           string                 // the converted name of the table
 	DBName         string                 // the original name of the table in the DB
 	Type           string                 // the table type (e.g. VIEW or BASE TABLE)
+	Kind           string                 // the kind of table (table, view, materialized view, partitioned, partition, or foreign)
 	IsView         bool                   // true if the table represents a view
 	IsInsertable   bool                   // true if the table accepts inserts (postgres only)
 	Comment        string                 // the comment attached to the table
+	Description    string                 // the comment without its annotations
+	Annotations    map[string]string      // the annotations parsed from the comment
+	PartitionKey   string                 // the partition key of a partitioned table (postgres only)
+	PartitionBound string                 // the partition bound of a partition (postgres only)
+	ViewDefinition string                 // the query defining the view, if the table is a view
 	Schema         *Schema                `yaml:"-" json:"-"` // the schema this table is in
+	Parent         *Table                 `yaml:"-" json:"-"` // the parent of a partition or inherited table (postgres only)
+	Children       Tables                 `yaml:"-" json:"-"` // the partitions of, or tables inheriting from, this table (postgres only)
 	Columns        Columns                // Database columns
 	ColumnsByName  map[string]*Column     `yaml:"-" json:"-"` // dbname to column
 	PrimaryKeys    Columns                // Primary Key Columns
 	Indexes        Indexes                // Table indexes
 	IndexesByName  map[string]*Index      `yaml:"-" json:"-"` // indexname to index
+	Triggers       Triggers               // Table triggers
 	ForeignKeys    ForeignKeys            // Foreign Keys
 	ForeignKeyRefs ForeignKeys            // Foreign Keys referencing this table
 	FKByName       map[string]*ForeignKey `yaml:"-" json:"-"` // Foreign Keys by foreign key name
 	FKRefsByName   map[string]*ForeignKey `yaml:"-" json:"-"` // Foreign Keys referencing this table by foreign key name
+	Relations      Relations              // relationships to other tables derived from foreign keys
+	IsJoinTable    bool                   // true if the table is the join table of a many-to-many relation
+	Params         map[string]interface{} // the params set for the table in the config's Overrides
+	Imports        Imports                // the imports needed by the types of the table's columns
 }
 
 // HasPrimaryKey returns true if Table has one or more primary keys.
@@ -72,6 +100,11 @@ func (t *Table) HasPrimaryKey() bool {
 	return len(t.PrimaryKeys) > 0
 }
 
+// HasTriggers returns true if Table has one or more triggers.
+func (t *Table) HasTriggers() bool {
+	return len(t.Triggers) > 0
+}
+
 // HasForeignKeys returns true if Table has one or more foreign keys.
 func (t *Table) HasForeignKeys() bool {
 	return len(t.ForeignKeys) > 0
@@ -82,6 +115,11 @@ func (t *Table) HasForeignKeyRefs() bool {
 	return len(t.ForeignKeyRefs) > 0
 }
 
+// HasRelations returns true if Table has one or more relations.
+func (t *Table) HasRelations() bool {
+	return len(t.Relations) > 0
+}
+
 // Column is the data about a DB column of a table.
 type Column struct {
 	Table              *Table                       `yaml:"-" json:"-"` // the table this column is in
@@ -89,12 +127,37 @@ type Column struct {
 	DBName             string                       // the original name of the column in the DB
 	Type               string                       // the converted name of the type
 	DBType             string                       // the original type of the column in the DB
+	TypeSource         string                       // what the Type was mapped with, e.g. TypeMap["int4"] or the name of a type rule
+	Types              map[string]string            // the converted names of the type from each of the named type maps, by map name
 	IsArray            bool                         // true if the column type is an array
+	ArrayDimensions    int                          // (postgres) the number of dimensions of an array type
+	IsRange            bool                         // (postgres) true if the column type is a range type
+	IsMultirange       bool                         // (postgres) true if the column type is a multirange type
+	RangeSubtype       string                       // (postgres) the element type of a range or multirange type
+	Extension          string                       // (postgres) the name of the extension the column type comes from, if any
+	GeometryType       string                       // (postgres) the geometry type of a PostGIS geometry or geography, e.g. Point
+	SRID               int                          // (postgres) the spatial reference ID of a PostGIS geometry or geography
 	Length             int                          // non-zero if the type has a length (e.g. varchar[16])
+	Precision          int                          // the precision of a numeric type, zero if not applicable
+	Scale              int                          // the scale of a numeric type, zero if not applicable
+	DatetimePrecision  int                          // the fractional seconds precision of a date or time type
+	CharacterSet       string                       // the character set of a character type, if any
+	Collation          string                       // the collation of a character type, if any
+	ColumnType         string                       // the full declared type of the column, e.g. numeric(12,2) or int(10) unsigned
+	Unsigned           bool                         // (mysql) true if the numeric type is unsigned
+	Zerofill           bool                         // (mysql) true if the numeric type is zerofill
 	UserDefined        bool                         // true if the type is user-defined
+	TypeSchema         string                       // (postgres) the original name of the schema of a user-defined type
+	Domain             *Domain                      `yaml:"-" json:"-"` // (postgres) the domain that is the column's type
+	CompositeType      *CompositeType               `yaml:"-" json:"-"` // (postgres) the composite type that is the column's type
+	Enum               *Enum                        `yaml:"-" json:"-"` // the enum that is the column's type, if any
+	Sequence           *Sequence                    `yaml:"-" json:"-"` // (postgres) the sequence owned by this column, if any
+	Source             *Column                      `yaml:"-" json:"-"` // the table column a view column is read from, if known
 	Nullable           bool                         // true if the column is not NON NULL
 	HasDefault         bool                         // true if the column has a default
 	Comment            string                       // the comment attached to the column
+	Description        string                       // the comment without its annotations
+	Annotations        map[string]string            // the annotations parsed from the comment
 	IsPrimaryKey       bool                         // true if the column is a primary key
 	Ordinal            int64                        // the column's ordinal position
 	IsFK               bool                         // true if the column is a foreign key
@@ -102,18 +165,26 @@ type Column struct {
 	FKColumn           *ForeignKeyColumn            // foreign key column definition
 	FKColumnRefs       ForeignKeyColumns            // all foreign key columns referencing this column
 	FKColumnRefsByName map[string]*ForeignKeyColumn `yaml:"-" json:"-"` // all foreign key columns referencing this column by foreign key name
+	Params             map[string]interface{}       // the params set for the column in the config's Overrides
 	Orig               interface{}                  `yaml:"-" json:"-"` // the raw database column data
 }
 
 // ForeignKey contains the
 type ForeignKey struct {
-	DBName         string            // the original name of the foreign key constraint in the db
-	Name           string            // the converted name of the foreign key constraint
-	TableDBName    string            // the original name of the table in the db
-	RefTableDBName string            // the original name of the foreign table in the db
-	Table          *Table            `yaml:"-" json:"-"` // the foreign key table
-	RefTable       *Table            `yaml:"-" json:"-"` // the foreign key foreign table
-	FKColumns      ForeignKeyColumns // all foreign key columns belonging to the foreign key
+	DBName            string            // the original name of the foreign key constraint in the db
+	Name              string            // the converted name of the foreign key constraint
+	TableDBName       string            // the original name of the table in the db
+	RefSchemaDBName   string            // the original name of the foreign table's schema in the db
+	RefTableDBName    string            // the original name of the foreign table in the db
+	Table             *Table            `yaml:"-" json:"-"` // the foreign key table
+	RefTable          *Table            `yaml:"-" json:"-"` // the foreign key foreign table
+	FKColumns         ForeignKeyColumns // all foreign key columns belonging to the foreign key
+	Nullable          bool              // true if any of the foreign key columns is nullable, so a row need not reference another row
+	IsDeferrable      bool              // (postgres) true if checking the constraint can be deferred to the end of the transaction
+	InitiallyDeferred bool              // (postgres) true if checking the constraint is deferred by default
+	Comment           string            // (postgres) the comment attached to the foreign key constraint
+	Description       string            // the comment without its annotations
+	Annotations       map[string]string // the annotations parsed from the comment
 }
 
 // ForeignKeyColumn contains the definition of a database foreign key at the kcolumn level
@@ -125,28 +196,166 @@ type ForeignKeyColumn struct {
 	RefColumn       *Column `yaml:"-" json:"-"` // the referenced column
 }
 
+// The kinds of relation between tables.
+const (
+	RelationBelongsTo  = "belongs-to"   // the table has a foreign key referencing the other table
+	RelationHasOne     = "has-one"      // the other table has a unique foreign key referencing the table
+	RelationHasMany    = "has-many"     // the other table has a foreign key referencing the table
+	RelationManyToMany = "many-to-many" // a join table has foreign keys referencing both tables
+)
+
+// Relation is a relationship from a table to another table, derived from the
+// foreign keys between them.
+type Relation struct {
+	Name            string        // the converted name of the relation
+	DBName          string        // the name the relation's Name is converted from, derived from the db names of the tables and columns involved
+	Kind            string        // the kind of relation: belongs-to, has-one, has-many or many-to-many
+	RefSchemaDBName string        // the original name of the related table's schema in the db
+	RefTableDBName  string        // the original name of the related table in the db
+	ThroughDBName   string        // the original name of the join table of a many-to-many relation
+	Table           *Table        `yaml:"-" json:"-"` // the table the relation is from
+	RefTable        *Table        `yaml:"-" json:"-"` // the related table
+	Through         *Table        `yaml:"-" json:"-"` // the join table of a many-to-many relation
+	Path            RelationSteps // the joins from Table to RefTable
+}
+
+// RelationStep is one join in the path of a relation, from the columns of one
+// table to the columns of another.
+type RelationStep struct {
+	TableDBName      string      // the original name of the table joined from
+	ColumnDBNames    Strings     // the original names of the columns joined from
+	RefTableDBName   string      // the original name of the table joined to
+	RefColumnDBNames Strings     // the original names of the columns joined to
+	Table            *Table      `yaml:"-" json:"-"` // the table joined from
+	Columns          Columns     `yaml:"-" json:"-"` // the columns joined from
+	RefTable         *Table      `yaml:"-" json:"-"` // the table joined to
+	RefColumns       Columns     `yaml:"-" json:"-"` // the columns joined to
+	ForeignKey       *ForeignKey `yaml:"-" json:"-"` // the foreign key joined on, which may point either way
+}
+
+// Cycle is a group of tables whose foreign keys reference each other in a
+// loop, so that no order of inserts satisfies all of them.  To put the tables
+// in order, some of the foreign keys are ignored, preferably ones that are
+// nullable or deferrable, so their rows can be inserted first and linked up
+// later.
+type Cycle struct {
+	Tables            Tables      `yaml:"-" json:"-"` // the tables in the cycle, in the order they were put in
+	TableDBNames      Strings     // the original names of the tables in the cycle, in the same order
+	ForeignKeys       ForeignKeys // the foreign keys between the tables in the cycle
+	BrokenForeignKeys ForeignKeys // the foreign keys ignored to put the tables in order
+}
+
 // Index is the data about a table index.
 type Index struct {
-	Name     string  // the converted name of the index
-	DBName   string  // dbname of the index
-	IsUnique bool    // true if index is unique
-	Columns  Columns // columns used in the index
+	Name        string            // the converted name of the index
+	DBName      string            // dbname of the index
+	IsUnique    bool              // true if index is unique
+	Columns     Columns           // columns used in the index
+	Comment     string            // (postgres) the comment attached to the index, or to its constraint
+	Description string            // the comment without its annotations
+	Annotations map[string]string // the annotations parsed from the comment
+}
+
+// Trigger is the data about a table trigger.
+type Trigger struct {
+	Name       string  // the converted name of the trigger
+	DBName     string  // the original name of the trigger in the DB
+	Table      *Table  `yaml:"-" json:"-"` // the table the trigger is on
+	Timing     string  // when the trigger fires: BEFORE, AFTER, or INSTEAD OF
+	Events     Strings // the events the trigger fires on: INSERT, UPDATE, DELETE, or TRUNCATE
+	ForEachRow bool    // true if the trigger fires once per row rather than once per statement
+	Function   string  // (postgres) the schema-qualified name of the function the trigger executes
+	Definition string  // the definition of the trigger (postgres) or its action statement (mysql)
+}
+
+// Sequence is the data about a DB sequence.
+type Sequence struct {
+	Name              string  // the converted name of the sequence
+	DBName            string  // the original name of the sequence in the DB
+	Schema            *Schema `yaml:"-" json:"-"` // the schema the sequence is in
+	DBType            string  // the original data type of the sequence in the DB
+	Start             int64   // the first value of the sequence
+	Increment         int64   // the amount the sequence is incremented by
+	Min               int64   // the minimum value of the sequence
+	Max               int64   // the maximum value of the sequence
+	Cycle             bool    // true if the sequence wraps around when it reaches its limit
+	TableSchemaDBName string  // the original name of the schema of the table owning the sequence in the DB, if any
+	TableDBName       string  // the original name of the table owning the sequence in the DB, if any
+	ColumnDBName      string  // the original name of the column owning the sequence in the DB, if any
+	Table             *Table  `yaml:"-" json:"-"` // the table owning the sequence, if any
+	Column            *Column `yaml:"-" json:"-"` // the column owning the sequence, if any
 }
 
 // Enum represents a type that has a set of allowed values.
 type Enum struct {
-	Name   string       // the converted name of the enum
-	DBName string       // the original name of the enum in the DB
-	Schema *Schema      `yaml:"-" json:"-"` // the schema the enum is in
-	Table  *Table       `yaml:"-" json:"-"` // (mysql) the table this enum is part of
-	Values []*EnumValue // the list of possible values for this enum
+	Name        string            // the converted name of the enum
+	DBName      string            // the original name of the enum in the DB
+	Schema      *Schema           `yaml:"-" json:"-"` // the schema the enum is in
+	Table       *Table            `yaml:"-" json:"-"` // (mysql) the table this enum is part of
+	IsSet       bool              // (mysql) true if the enum is a SET, whose values are bit flags
+	Values      []*EnumValue      // the list of possible values for this enum
+	Columns     Columns           `yaml:"-" json:"-"` // the columns that use this enum
+	Comment     string            // (postgres) the comment attached to the enum type
+	Description string            // the comment without its annotations
+	Annotations map[string]string // the annotations parsed from the comment
 }
 
 // EnumValue is one of the named values for an enum.
 type EnumValue struct {
 	Name   string // the converted label of the enum
 	DBName string // the original label of the enum in the DB
-	Value  int    // the value for this enum value (order)
+	Value  int    // the value for this enum value (order, or the bit flag for a set)
+}
+
+// CompositeType is a user-defined type made up of a list of named attributes.
+type CompositeType struct {
+	Name       string              // the converted name of the type
+	DBName     string              // the original name of the type in the DB
+	Schema     *Schema             `yaml:"-" json:"-"` // the schema the type is in
+	Attributes CompositeAttributes // ordered list of attributes in this type
+}
+
+// CompositeAttribute is a single named attribute of a composite type.
+type CompositeAttribute struct {
+	Name        string // the converted name of the attribute
+	DBName      string // the original name of the attribute in the DB
+	Type        string // the converted name of the type
+	DBType      string // the original type of the attribute in the DB
+	IsArray     bool   // true if the attribute type is an array
+	Length      int    // non-zero if the type has a length (e.g. varchar[16])
+	UserDefined bool   // true if the type is user-defined
+	Nullable    bool   // true if the attribute is not NON NULL
+	Ordinal     int64  // the attribute's ordinal position
+}
+
+// Domain is a user-defined type based on another type, with optional
+// constraints on its values.
+type Domain struct {
+	Name        string            // the converted name of the domain
+	DBName      string            // the original name of the domain in the DB
+	Schema      *Schema           `yaml:"-" json:"-"` // the schema the domain is in
+	Type        string            // the converted name of the underlying type
+	BaseType    string            // the original name of the underlying type in the DB
+	IsArray     bool              // true if the underlying type is an array
+	Length      int               // non-zero if the underlying type has a length (e.g. varchar[16])
+	UserDefined bool              // true if the underlying type is user-defined
+	Nullable    bool              // true if the domain is not NOT NULL
+	HasDefault  bool              // true if the domain has a default
+	Default     string            // the default expression for the domain
+	Constraints DomainConstraints // the check constraints on the domain
+}
+
+// DomainConstraint is a check constraint attached to a domain.
+type DomainConstraint struct {
+	Name       string // the converted name of the constraint
+	DBName     string // the original name of the constraint in the DB
+	Definition string // the definition of the constraint (e.g. CHECK (VALUE > 0))
+}
+
+// Import is a package that code using the mapped types must import.
+type Import struct {
+	Path  string // the import path of the package
+	Alias string // the name to import the package as, if any
 }
 
 // ConfigData holds the portion of the config that will be available to
@@ -186,9 +395,12 @@ type ConfigData struct {
 	// (generally types from your language for deserialization).  Types not in
 	// this list will remain in their database form.  In the data sent to your
 	// template, this is the Column.Type, and the original type is in
-	// Column.OrigType.  Note that because of the way tables in TOML work,
-	// TypeMap and NullableTypeMap must be at the end of your configuration
-	// file.
+	// Column.OrigType.  Keys may also be a full column type such as
+	// "numeric(12,2)", a schema-qualified user-defined type such as
+	// "billing.currency", or for mysql a type with modifiers such as "int
+	// unsigned"; the most specific key that exists is used.  Note that because
+	// of the way tables in TOML work, TypeMap and NullableTypeMap must be at
+	// the end of your configuration file.
 	TypeMap map[string]string
 
 	// NullableTypeMap is a mapping of database type names to replacement type
@@ -201,6 +413,25 @@ type ConfigData struct {
 	// file.
 	NullableTypeMap map[string]string
 
+	// TypeMaps are named type maps, each mapping database type names to the
+	// types of another language the same way TypeMap does.  In the data sent
+	// to your template, the type from each map is in Column.Types under the
+	// map's name.
+	TypeMaps map[string]map[string]string
+
+	// NullableTypeMaps are the named type maps for nullable columns, the same
+	// way NullableTypeMap is for TypeMap.
+	NullableTypeMaps map[string]map[string]string
+
+	// TypeImports maps the types columns are mapped to, or the package
+	// qualifiers of those types (e.g. "uuid" for "uuid.UUID"), to the package
+	// that declares them.
+	TypeImports map[string]Import
+
+	// MergeMySQLEnums, if true, merges mysql enums (or sets) in the same schema
+	// that have identical lists of values into a single enum.
+	MergeMySQLEnums bool
+
 	// PluginDirs a set of absolute/relative  paths that will be used for
 	// plugin lookup.
 	PluginDirs []string
@@ -289,6 +520,42 @@ func (fk ForeignKeys) Names() Strings {
 	return names
 }
 
+// Relations is a list of Relation.
+type Relations []*Relation
+
+// Names returns the list of converted relation names.
+func (r Relations) Names() Strings {
+	names := make(Strings, len(r))
+	for x := range r {
+		names[x] = r[x].Name
+	}
+	return names
+}
+
+// DBNames returns the list of relation names the converted names are made
+// from.
+func (r Relations) DBNames() Strings {
+	names := make(Strings, len(r))
+	for x := range r {
+		names[x] = r[x].DBName
+	}
+	return names
+}
+
+// OfKind returns the relations of the given kind.
+func (r Relations) OfKind(kind string) Relations {
+	var ret Relations
+	for _, rel := range r {
+		if rel.Kind == kind {
+			ret = append(ret, rel)
+		}
+	}
+	return ret
+}
+
+// RelationSteps is the list of joins in the path of a relation.
+type RelationSteps []*RelationStep
+
 // ForeignKeyColumns represents a list of ForeignKeyColumn
 type ForeignKeyColumns []*ForeignKeyColumn
 
@@ -385,6 +652,16 @@ func (t Tables) DBNames() Strings {
 	return names
 }
 
+// Reverse returns a copy of the tables in reverse order, e.g. to turn the
+// insert order of OrderedTables into a delete order.
+func (t Tables) Reverse() Tables {
+	ret := make(Tables, len(t))
+	for x := range t {
+		ret[len(t)-1-x] = t[x]
+	}
+	return ret
+}
+
 // Enums represents all the enums in a schema.
 type Enums []*Enum
 
@@ -426,3 +703,133 @@ func (i Indexes) Names() Strings {
 	}
 	return names
 }
+
+// Imports is a list of imports, sorted by path.
+type Imports []*Import
+
+// Paths returns the list of import paths.
+func (i Imports) Paths() Strings {
+	paths := make(Strings, len(i))
+	for x := range i {
+		paths[x] = i[x].Path
+	}
+	return paths
+}
+
+// CompositeTypes represents all the composite types in a schema.
+type CompositeTypes []*CompositeType
+
+// Names returns the list of composite type Names in this schema.
+func (c CompositeTypes) Names() Strings {
+	names := make(Strings, len(c))
+	for x := range c {
+		names[x] = c[x].Name
+	}
+	return names
+}
+
+// DBNames returns the list of composite type DBNames in this schema.
+func (c CompositeTypes) DBNames() Strings {
+	names := make(Strings, len(c))
+	for x := range c {
+		names[x] = c[x].DBName
+	}
+	return names
+}
+
+// CompositeAttributes represents the ordered list of attributes in a composite
+// type.
+type CompositeAttributes []*CompositeAttribute
+
+// Names returns the ordered list of attribute Names in this type.
+func (a CompositeAttributes) Names() Strings {
+	names := make(Strings, len(a))
+	for x := range a {
+		names[x] = a[x].Name
+	}
+	return names
+}
+
+// DBNames returns the ordered list of attribute DBNames in this type.
+func (a CompositeAttributes) DBNames() Strings {
+	names := make(Strings, len(a))
+	for x := range a {
+		names[x] = a[x].DBName
+	}
+	return names
+}
+
+// Domains represents all the domains in a schema.
+type Domains []*Domain
+
+// Names returns the list of domain Names in this schema.
+func (d Domains) Names() Strings {
+	names := make(Strings, len(d))
+	for x := range d {
+		names[x] = d[x].Name
+	}
+	return names
+}
+
+// DBNames returns the list of domain DBNames in this schema.
+func (d Domains) DBNames() Strings {
+	names := make(Strings, len(d))
+	for x := range d {
+		names[x] = d[x].DBName
+	}
+	return names
+}
+
+// DomainConstraints represents the list of check constraints on a domain.
+type DomainConstraints []*DomainConstraint
+
+// DBNames returns the list of constraint DBNames on this domain.
+func (d DomainConstraints) DBNames() Strings {
+	names := make(Strings, len(d))
+	for x := range d {
+		names[x] = d[x].DBName
+	}
+	return names
+}
+
+// Triggers represents all the triggers on a table.
+type Triggers []*Trigger
+
+// Names returns the list of trigger Names on this table.
+func (t Triggers) Names() Strings {
+	names := make(Strings, len(t))
+	for x := range t {
+		names[x] = t[x].Name
+	}
+	return names
+}
+
+// DBNames returns the list of trigger DBNames on this table.
+func (t Triggers) DBNames() Strings {
+	names := make(Strings, len(t))
+	for x := range t {
+		names[x] = t[x].DBName
+	}
+	return names
+}
+
+// Sequences represents all the sequences in a schema.
+type Sequences []*Sequence
+
+// Names returns the list of sequence Names in this schema.
+func (s Sequences) Names() Strings {
+	names := make(Strings, len(s))
+	for x := range s {
+		names[x] = s[x].Name
+	}
+	return names
+}
+
+// DBNames returns the list of sequence DBNames in this schema.
+func (s Sequences) DBNames() Strings {
+	names := make(Strings, len(s))
+	for x := range s {
+		names[x] = s[x].DBName
+	}
+	return names
+}
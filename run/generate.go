@@ -21,50 +21,60 @@ import (
 )
 
 // Generate reads your database, gets the schema for it, and then generates
-// files based on your templates and your configuration.
-func Generate(env environ.Values, cfg *Config) error {
-	info, err := cfg.Driver.Parse(env.Log, cfg.ConnStr, cfg.Schemas, makeFilter(cfg.IncludeTables, cfg.ExcludeTables))
+// files based on your templates and your configuration.  Reading the database
+// stops with an error if the context is cancelled.
+func Generate(ctx context.Context, env environ.Values, cfg *Config) error {
+	info, err := parseDB(ctx, env, cfg)
 	if err != nil {
 		return err
 	}
-	db, err := makeData(env.Log, info, cfg)
+	db, err := makeData(env, info, cfg)
+	if err != nil {
+		return err
+	}
+	dbs, err := typeMapData(cfg, info, db)
 	if err != nil {
 		return err
 	}
 	if len(cfg.SchemaPaths) == 0 {
 		env.Log.Println("No SchemaPaths specified, skipping schemas.")
 	} else {
-		if err := generateSchemas(env, cfg, db); err != nil {
+		if err := generateSchemas(env, cfg, dbs); err != nil {
 			return err
 		}
 	}
 	if len(cfg.EnumPaths) == 0 {
 		env.Log.Println("No EnumPath specified, skipping enums.")
 	} else {
-		if err := generateEnums(env, cfg, db); err != nil {
+		if err := generateEnums(env, cfg, dbs); err != nil {
 			return err
 		}
 	}
 	if len(cfg.TablePaths) == 0 {
 		env.Log.Println("No table path specified, skipping tables.")
 	} else {
-		if err := generateTables(env, cfg, db); err != nil {
+		if err := generateTables(env, cfg, dbs); err != nil {
 			return err
 		}
 	}
 	return copyStaticFiles(env, cfg.StaticDir, cfg.OutputDir)
 }
 
-func generateSchemas(env environ.Values, cfg *Config, db *data.DBData) error {
-	for _, schema := range db.Schemas {
+// The generate functions render each output target with the data for its
+// type map, from dbs, which holds the same schemas, tables and enums in the
+// same order for every type map.
+
+func generateSchemas(env environ.Values, cfg *Config, dbs map[string]*data.DBData) error {
+	for x, schema := range dbs[""].Schemas {
 		fileData := struct{ Schema string }{Schema: schema.Name}
-		contents := data.SchemaData{
-			Schema: schema,
-			DB:     db,
-			Config: cfg.ConfigData,
-			Params: cfg.Params,
-		}
 		for _, target := range cfg.SchemaPaths {
+			db := dbs[target.TypeMap]
+			contents := data.SchemaData{
+				Schema: db.Schemas[x],
+				DB:     db,
+				Config: cfg.ConfigData,
+				Params: cfg.Params,
+			}
 			env.Log.Printf("Generating output for schema %v", schema.Name)
 			if err := genFile(env, fileData, contents, target, cfg.NoOverwriteGlobs, cfg.PostRun, cfg.OutputDir, cfg.TemplateEngine); err != nil {
 				return errors.WithMessage(err, "generating file for schema "+schema.Name)
@@ -80,17 +90,18 @@ type templateEngine struct {
 	UseStdout   bool
 }
 
-func generateEnums(env environ.Values, cfg *Config, db *data.DBData) error {
-	for _, schema := range db.Schemas {
-		for _, enum := range schema.Enums {
+func generateEnums(env environ.Values, cfg *Config, dbs map[string]*data.DBData) error {
+	for x, schema := range dbs[""].Schemas {
+		for y, enum := range schema.Enums {
 			fileData := struct{ Schema, Enum, Table string }{Schema: schema.Name, Enum: enum.Name, Table: enum.Table.DBName}
-			contents := data.EnumData{
-				Enum:   enum,
-				DB:     db,
-				Config: cfg.ConfigData,
-				Params: cfg.Params,
-			}
 			for _, target := range cfg.EnumPaths {
+				db := dbs[target.TypeMap]
+				contents := data.EnumData{
+					Enum:   db.Schemas[x].Enums[y],
+					DB:     db,
+					Config: cfg.ConfigData,
+					Params: cfg.Params,
+				}
 				if err := genFile(env, fileData, contents, target, cfg.NoOverwriteGlobs, cfg.PostRun, cfg.OutputDir, cfg.TemplateEngine); err != nil {
 					env.Log.Printf("Generating output for enum %v", enum.Name)
 					return errors.WithMessage(err, "generating file for enum "+enum.Name)
@@ -101,17 +112,18 @@ func generateEnums(env environ.Values, cfg *Config, db *data.DBData) error {
 	return nil
 }
 
-func generateTables(env environ.Values, cfg *Config, db *data.DBData) error {
-	for _, schema := range db.Schemas {
-		for _, table := range schema.Tables {
-			contents := data.TableData{
-				Table:  table,
-				DB:     db,
-				Config: cfg.ConfigData,
-				Params: cfg.Params,
-			}
+func generateTables(env environ.Values, cfg *Config, dbs map[string]*data.DBData) error {
+	for x, schema := range dbs[""].Schemas {
+		for y, table := range schema.Tables {
 			fileData := struct{ Schema, Table string }{Schema: schema.Name, Table: table.Name}
 			for _, target := range cfg.TablePaths {
+				db := dbs[target.TypeMap]
+				contents := data.TableData{
+					Table:  db.Schemas[x].Tables[y],
+					DB:     db,
+					Config: cfg.ConfigData,
+					Params: cfg.Params,
+				}
 				if err := genFile(env, fileData, contents, target, cfg.NoOverwriteGlobs, cfg.PostRun, cfg.OutputDir, cfg.TemplateEngine); err != nil {
 					env.Log.Printf("Generating output for table %v", table.Name)
 					return errors.WithMessage(err, "generating file for table "+table.Name)
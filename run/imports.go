@@ -0,0 +1,100 @@
+package run
+
+import (
+	"go/ast"
+	"go/parser"
+	"sort"
+
+	"gnorm.org/gnorm/run/data"
+)
+
+// typeImports returns the imports of the packages that declare the types
+// used in typ, from the TypeImports entry for each type or for its package
+// qualifier.  typ is parsed as a Go type expression, so every qualified type
+// in it counts, e.g. both of map[uuid.UUID]dec.Decimal or func(*big.Int)
+// chan<- time.Time.  A type that isn't valid Go, e.g. of another language,
+// is looked up as a whole.
+func typeImports(cfg *Config, typ string) []data.Import {
+	expr, err := parser.ParseExpr(typ)
+	if err != nil {
+		if imp, ok := cfg.TypeImports[typ]; ok {
+			return []data.Import{imp}
+		}
+		return nil
+	}
+	var ret []data.Import
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			pkg, ok := n.X.(*ast.Ident)
+			if !ok {
+				return true
+			}
+			if imp, ok := cfg.TypeImports[pkg.Name+"."+n.Sel.Name]; ok {
+				ret = append(ret, imp)
+			} else if imp, ok := cfg.TypeImports[pkg.Name]; ok {
+				ret = append(ret, imp)
+			}
+			return false
+		case *ast.Ident:
+			if imp, ok := cfg.TypeImports[n.Name]; ok {
+				ret = append(ret, imp)
+			}
+		}
+		return true
+	})
+	return ret
+}
+
+// setImports sets the Imports of every table from the types of its columns,
+// and of every schema from the imports of its tables and the types of its
+// domains and composite types.
+func setImports(cfg *Config, db *data.DBData) {
+	if len(cfg.TypeImports) == 0 {
+		return
+	}
+	for _, sch := range db.Schemas {
+		schema := map[data.Import]bool{}
+		add := func(imports map[data.Import]bool, typ string) {
+			for _, imp := range typeImports(cfg, typ) {
+				imports[imp] = true
+			}
+		}
+		for _, t := range sch.Tables {
+			table := map[data.Import]bool{}
+			for _, c := range t.Columns {
+				add(table, c.Type)
+				add(schema, c.Type)
+			}
+			t.Imports = sortImports(table)
+		}
+		for _, d := range sch.Domains {
+			add(schema, d.Type)
+		}
+		for _, ct := range sch.CompositeTypes {
+			for _, a := range ct.Attributes {
+				add(schema, a.Type)
+			}
+		}
+		sch.Imports = sortImports(schema)
+	}
+}
+
+// sortImports returns the imports sorted by path, then alias.
+func sortImports(imports map[data.Import]bool) data.Imports {
+	if len(imports) == 0 {
+		return nil
+	}
+	ret := make(data.Imports, 0, len(imports))
+	for imp := range imports {
+		imp := imp
+		ret = append(ret, &imp)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Path != ret[j].Path {
+			return ret[i].Path < ret[j].Path
+		}
+		return ret[i].Alias < ret[j].Alias
+	})
+	return ret
+}
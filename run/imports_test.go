@@ -0,0 +1,113 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+func TestMakeDataImports(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{
+				"uuid":        "uuid.UUID",
+				"uuid[]":      "[]*uuid.UUID",
+				"timestamptz": "time.Time",
+				"numeric":     "dec.Decimal",
+				"integer":     "int",
+				"hstore":      "map[uuid.UUID]dec.Decimal",
+				"tsrange":     "chan [2]time.Time",
+			},
+			NullableTypeMap: map[string]string{"text": "sql.NullString", "integer": "sql.NullInt64"},
+			TypeMaps:        map[string]map[string]string{"ts": {"uuid": "string"}},
+			TypeImports: map[string]data.Import{
+				"uuid.UUID":   {Path: "github.com/google/uuid"},
+				"time":        {Path: "time"},
+				"sql":         {Path: "database/sql"},
+				"dec.Decimal": {Path: "github.com/shopspring/decimal", Alias: "dec"},
+			},
+		},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{
+					{Name: "id", Type: "uuid"},
+					{Name: "friends", Type: "uuid", IsArray: true},
+					{Name: "name", Type: "text", Nullable: true},
+					{Name: "age", Type: "integer", Nullable: true},
+				},
+			}, {
+				Name: "payments",
+				Columns: []*database.Column{
+					{Name: "amount", Type: "numeric"},
+					{Name: "paid_at", Type: "timestamptz"},
+					{Name: "count", Type: "integer"},
+				},
+			}, {
+				Name: "ledgers",
+				Columns: []*database.Column{
+					{Name: "balances", Type: "hstore"},
+					{Name: "periods", Type: "tsrange"},
+				},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	describe := func(imports data.Imports) []string {
+		var ret []string
+		for _, imp := range imports {
+			ret = append(ret, imp.Alias+" "+imp.Path)
+		}
+		return ret
+	}
+	tests := []struct {
+		what     string
+		imports  data.Imports
+		expected []string
+	}{
+		{"users", sch.TablesByName["users"].Imports, []string{" database/sql", " github.com/google/uuid"}},
+		{"payments", sch.TablesByName["payments"].Imports, []string{"dec github.com/shopspring/decimal", " time"}},
+		{"ledgers", sch.TablesByName["ledgers"].Imports, []string{" github.com/google/uuid", "dec github.com/shopspring/decimal", " time"}},
+		{"schema", sch.Imports, []string{" database/sql", " github.com/google/uuid", "dec github.com/shopspring/decimal", " time"}},
+	}
+	for _, test := range tests {
+		got := describe(test.imports)
+		if len(got) != len(test.expected) {
+			t.Errorf("%s: expected imports %q, got %q", test.what, test.expected, got)
+			continue
+		}
+		for x := range got {
+			if got[x] != test.expected[x] {
+				t.Errorf("%s: expected imports %q, got %q", test.what, test.expected, got)
+				break
+			}
+		}
+	}
+
+	ts, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c.forTypeMap("ts"))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if got := describe(ts.SchemasByName["public"].TablesByName["users"].Imports); len(got) != 0 {
+		t.Errorf("expected no imports for the ts type map, got %q", got)
+	}
+	if got := describe(sch.TablesByName["users"].Imports); len(got) != 2 {
+		t.Errorf("expected the default imports to be unchanged, got %q", got)
+	}
+}
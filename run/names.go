@@ -0,0 +1,205 @@
+package run
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gnorm.org/gnorm/run/data"
+)
+
+// The ways converted names that collide, or are reserved words, may be
+// handled.
+const (
+	// CollisionError fails with an error naming the DB objects involved.
+	CollisionError = "error"
+	// CollisionSuffix appends a number to names that collide with a name
+	// earlier in the same scope, and an underscore to reserved words.
+	CollisionSuffix = "suffix"
+	// CollisionWarn prints a warning to stderr and leaves the names as they
+	// are.  It's the default.
+	CollisionWarn = "warn"
+)
+
+// reservedWords are the lists of reserved words of the languages gnorm
+// commonly generates code for.
+var reservedWords = map[string][]string{
+	"go": {
+		"break", "case", "chan", "const", "continue", "default", "defer",
+		"else", "fallthrough", "for", "func", "go", "goto", "if", "import",
+		"interface", "map", "package", "range", "return", "select", "struct",
+		"switch", "type", "var",
+	},
+	"typescript": {
+		"any", "as", "boolean", "break", "case", "catch", "class", "const",
+		"constructor", "continue", "debugger", "declare", "default", "delete",
+		"do", "else", "enum", "export", "extends", "false", "finally", "for",
+		"from", "function", "get", "if", "implements", "import", "in",
+		"instanceof", "interface", "let", "module", "new", "null", "number",
+		"of", "package", "private", "protected", "public", "require", "return",
+		"set", "static", "string", "super", "switch", "symbol", "this", "throw",
+		"true", "try", "type", "typeof", "var", "void", "while", "with",
+		"yield",
+	},
+	"python": {
+		"False", "None", "True", "and", "as", "assert", "async", "await",
+		"break", "class", "continue", "def", "del", "elif", "else", "except",
+		"finally", "for", "from", "global", "if", "import", "in", "is",
+		"lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try",
+		"while", "with", "yield",
+	},
+}
+
+// ReservedWords returns the reserved words of the given language, one of
+// ReservedLanguages.
+func ReservedWords(language string) ([]string, error) {
+	words, ok := reservedWords[strings.ToLower(language)]
+	if !ok {
+		return nil, errors.Errorf("unknown reserved word language %q, expected one of %q", language, ReservedLanguages())
+	}
+	return words, nil
+}
+
+// ReservedLanguages returns the languages ReservedWords has reserved words
+// for, sorted.
+func ReservedLanguages() []string {
+	langs := make([]string, 0, len(reservedWords))
+	for lang := range reservedWords {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// namedItem is an item in a scope of names that must be unique, like the
+// columns of a table.
+type namedItem struct {
+	what string  // the kind of item and its qualified DB name, for messages
+	name *string // the item's converted name
+}
+
+// checkNames checks that the converted names are unique within each scope,
+// and aren't reserved words, and handles the names that fail as the
+// NameCollisions strategy of the config says.  Empty names are problems.  The
+// scopes are the schemas, the tables and enums of each schema, which usually
+// become types in the same package, the columns of each table, and the values
+// of each enum.  Warnings are written to stderr, if it isn't nil.  In strict
+// mode, names that collide or are reserved words are problems unless they're
+// suffixed, so that makeData fails with all of them and the other problems at
+// once.
+func checkNames(stderr io.Writer, probs *problems, cfg *Config, db *data.DBData) error {
+	reserved := make(map[string]bool, len(cfg.ReservedWords))
+	for _, w := range cfg.ReservedWords {
+		reserved[w] = true
+	}
+	strategy := cfg.NameCollisions
+	warn := func(format string, args ...interface{}) {
+		if stderr != nil {
+			fmt.Fprintf(stderr, "Warning: "+format+"\n", args...)
+		}
+	}
+	if cfg.Strict {
+		if strategy != CollisionSuffix {
+			strategy = CollisionWarn
+		}
+		warn = probs.add
+	}
+	check := func(items []namedItem) error {
+		for _, item := range items {
+			if *item.name == "" {
+				probs.add("Name conversion of %s resulted in an empty name", item.what)
+			}
+		}
+		return checkScope(warn, strategy, reserved, items)
+	}
+
+	var schemas []namedItem
+	for _, sch := range db.Schemas {
+		schemas = append(schemas, namedItem{"schema " + sch.DBName, &sch.Name})
+	}
+	if err := check(schemas); err != nil {
+		return err
+	}
+	for _, sch := range db.Schemas {
+		var types []namedItem
+		for _, t := range sch.Tables {
+			types = append(types, namedItem{"table " + sch.DBName + "." + t.DBName, &t.Name})
+		}
+		for _, e := range sch.Enums {
+			types = append(types, namedItem{"enum " + sch.DBName + "." + e.DBName, &e.Name})
+		}
+		if err := check(types); err != nil {
+			return err
+		}
+		for _, t := range sch.Tables {
+			var columns []namedItem
+			for _, c := range t.Columns {
+				columns = append(columns, namedItem{"column " + sch.DBName + "." + t.DBName + "." + c.DBName, &c.Name})
+			}
+			if err := check(columns); err != nil {
+				return err
+			}
+		}
+		for _, e := range sch.Enums {
+			var values []namedItem
+			for _, v := range e.Values {
+				values = append(values, namedItem{"value " + v.DBName + " of enum " + sch.DBName + "." + e.DBName, &v.Name})
+			}
+			if err := check(values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkScope checks the names of the items in one scope.  Unless the strategy
+// is CollisionError or CollisionSuffix, names that fail are passed to warn.
+func checkScope(warn func(format string, args ...interface{}), strategy string, reserved map[string]bool, items []namedItem) error {
+	for _, item := range items {
+		if !reserved[*item.name] {
+			continue
+		}
+		switch strategy {
+		case CollisionSuffix:
+			*item.name += "_"
+		case CollisionError:
+			return errors.Errorf("%s has the name %q, which is a reserved word", item.what, *item.name)
+		default:
+			warn("%s has the name %q, which is a reserved word", item.what, *item.name)
+		}
+	}
+
+	// the first item with a name keeps it.
+	taken := make(map[string]namedItem, len(items))
+	for _, item := range items {
+		if _, ok := taken[*item.name]; !ok {
+			taken[*item.name] = item
+		}
+	}
+	for _, item := range items {
+		first := taken[*item.name]
+		if first.name == item.name {
+			continue
+		}
+		switch strategy {
+		case CollisionSuffix:
+			name := *item.name
+			for n := 2; ; n++ {
+				candidate := fmt.Sprintf("%s%d", name, n)
+				if _, ok := taken[candidate]; !ok {
+					*item.name = candidate
+					taken[candidate] = item
+					break
+				}
+			}
+		case CollisionError:
+			return errors.Errorf("%s and %s both have the name %q", first.what, item.what, *item.name)
+		default:
+			warn("%s and %s both have the name %q", first.what, item.what, *item.name)
+		}
+	}
+	return nil
+}
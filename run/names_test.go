@@ -0,0 +1,140 @@
+package run
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+)
+
+func collidingInfo() *database.Info {
+	return &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{
+					{Name: "user_id", Type: "integer"},
+					{Name: "userId", Type: "integer"},
+					{Name: "user_id_2", Type: "integer"},
+					{Name: "type", Type: "text"},
+				},
+			}},
+			Enums: []*database.Enum{{
+				Name:   "kind",
+				Values: []*database.EnumValue{{Name: "URL"}, {Name: "url"}},
+			}},
+		}},
+	}
+}
+
+func TestMakeDataNameCollisionError(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{camel .}}`)),
+		NameCollisions: CollisionError,
+	}
+	_, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, collidingInfo(), c)
+	if err == nil {
+		t.Fatal("expected error for colliding names, but got none")
+	}
+	expected := `column public.users.user_id and column public.users.userId both have the name "userID"`
+	if err.Error() != expected {
+		t.Errorf("expected error %q, got %q", expected, err)
+	}
+
+	// warn is the default, and warns on stderr even if the log is discarded,
+	// as it is without -verbose.
+	c.ReservedWords = []string{"type"}
+	c.NameCollisions = ""
+	var stderr bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(ioutil.Discard, "", 0), Stderr: &stderr}, collidingInfo(), c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if got := strings.Join(db.Schemas[0].Tables[0].Columns.Names(), " "); got != "userID userID userID2 type" {
+		t.Errorf("expected names to be left alone, got %q", got)
+	}
+	for _, msg := range []string{
+		`column public.users.user_id and column public.users.userId both have the name "userID"`,
+		`value URL of enum public.kind and value url of enum public.kind both have the name "url"`,
+		`column public.users.type has the name "type", which is a reserved word`,
+	} {
+		if !strings.Contains(stderr.String(), "Warning: "+msg+"\n") {
+			t.Errorf("expected warning %q, got %q", msg, stderr.String())
+		}
+	}
+}
+
+func TestMakeDataNameCollisionSuffix(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{camel .}}`)),
+		NameCollisions: CollisionSuffix,
+	}
+	words, err := ReservedWords("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.ReservedWords = words
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, collidingInfo(), c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	// userID2 is already taken by user_id_2, so the second userID gets a 3.
+	if got := strings.Join(db.Schemas[0].Tables[0].Columns.Names(), " "); got != "userID userID3 userID2 type_" {
+		t.Errorf("unexpected column names %q", got)
+	}
+	values := db.Schemas[0].Enums[0].Values
+	if values[0].Name != "url" || values[1].Name != "url2" {
+		t.Errorf("unexpected enum value names %q and %q", values[0].Name, values[1].Name)
+	}
+}
+
+func TestMakeDataNameCollisionTableEnum(t *testing.T) {
+	t.Parallel()
+
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name:   "public",
+			Tables: []*database.Table{{Name: "status", Columns: []*database.Column{idColumn()}}},
+			Enums:  []*database.Enum{{Name: "Status", Values: []*database.EnumValue{{Name: "on"}}}},
+		}},
+	}
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{pascal .}}`)),
+		NameCollisions: CollisionError,
+	}
+	_, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	expected := `table public.status and enum public.Status both have the name "Status"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("expected error %q, got %v", expected, err)
+	}
+
+	c.NameCollisions = CollisionSuffix
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if table, enum := db.Schemas[0].Tables[0].Name, db.Schemas[0].Enums[0].Name; table != "Status" || enum != "Status2" {
+		t.Errorf("expected table Status and enum Status2, got %q and %q", table, enum)
+	}
+}
+
+func TestReservedWords(t *testing.T) {
+	for _, lang := range ReservedLanguages() {
+		if _, err := ReservedWords(lang); err != nil {
+			t.Errorf("%s: unexpected error %v", lang, err)
+		}
+	}
+	if _, err := ReservedWords("cobol"); err == nil {
+		t.Error("expected error for unknown language, but got none")
+	}
+}
@@ -0,0 +1,218 @@
+package run
+
+import (
+	"log"
+
+	"gnorm.org/gnorm/run/data"
+)
+
+// orderTables puts the tables of all schemas in foreign key dependency order,
+// referenced tables before the tables that reference them, so that inserting
+// rows in that order satisfies the foreign keys.  Tables that aren't
+// constrained by each other keep the order they were read in.  A table
+// referencing itself doesn't affect the order, since that only constrains the
+// order of its rows, and isn't recorded as a cycle.
+//
+// Tables whose foreign keys reference each other in a loop are recorded as
+// cycles, and put in order by ignoring some of those foreign keys, preferring
+// nullable or deferrable ones.  It must run after the foreign keys have been
+// mapped.
+func orderTables(log *log.Logger, db *data.DBData) {
+	var tables data.Tables
+	index := map[*data.Table]int{}
+	for _, sch := range db.Schemas {
+		for _, t := range sch.Tables {
+			index[t] = len(tables)
+			tables = append(tables, t)
+		}
+	}
+
+	// deps holds the foreign keys each table needs satisfied before its rows
+	// can be inserted.
+	deps := make([][]*data.ForeignKey, len(tables))
+	for x, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			if _, ok := index[fk.RefTable]; ok && fk.RefTable != t {
+				deps[x] = append(deps[x], fk)
+			}
+		}
+	}
+	comp := components(tables, index, deps)
+
+	placed := make([]bool, len(tables))
+	broken := map[*data.ForeignKey]bool{}
+	// blocking returns the foreign keys that keep table x from being placed.
+	blocking := func(x int) []*data.ForeignKey {
+		var ret []*data.ForeignKey
+		for _, fk := range deps[x] {
+			if !broken[fk] && !placed[index[fk.RefTable]] {
+				ret = append(ret, fk)
+			}
+		}
+		return ret
+	}
+
+	order := make(data.Tables, 0, len(tables))
+	for len(order) < len(tables) {
+		next := -1
+		for x := range tables {
+			if !placed[x] && len(blocking(x)) == 0 {
+				next = x
+				break
+			}
+		}
+		if next == -1 {
+			next = breakCycle(log, tables, index, comp, placed, blocking, broken)
+		}
+		placed[next] = true
+		order = append(order, tables[next])
+	}
+	db.OrderedTables = order
+	db.OrderedTableDBNames = order.DBNames()
+
+	cycles := map[int]*data.Cycle{}
+	for _, t := range order {
+		c := comp[index[t]]
+		if c == -1 {
+			continue
+		}
+		cycle, ok := cycles[c]
+		if !ok {
+			cycle = &data.Cycle{}
+			cycles[c] = cycle
+			db.Cycles = append(db.Cycles, cycle)
+		}
+		cycle.Tables = append(cycle.Tables, t)
+		cycle.TableDBNames = append(cycle.TableDBNames, t.DBName)
+		for _, fk := range deps[index[t]] {
+			if comp[index[fk.RefTable]] != c {
+				continue
+			}
+			cycle.ForeignKeys = append(cycle.ForeignKeys, fk)
+			if broken[fk] {
+				cycle.BrokenForeignKeys = append(cycle.BrokenForeignKeys, fk)
+				db.BrokenForeignKeys = append(db.BrokenForeignKeys, fk)
+			}
+		}
+	}
+
+	for _, sch := range db.Schemas {
+		for _, t := range order {
+			if t.Schema == sch {
+				sch.OrderedTables = append(sch.OrderedTables, t)
+				sch.OrderedTableDBNames = append(sch.OrderedTableDBNames, t.DBName)
+			}
+		}
+		for _, cycle := range db.Cycles {
+			for _, t := range cycle.Tables {
+				if t.Schema == sch {
+					sch.Cycles = append(sch.Cycles, cycle)
+					break
+				}
+			}
+		}
+	}
+}
+
+// breakCycle picks a table in a cycle to place next when no table is free of
+// unplaced dependencies, and marks the foreign keys keeping it from being
+// placed as broken.  It picks the first table whose blocking foreign keys are
+// all within its cycle, and all nullable or deferrable if there is one.
+func breakCycle(log *log.Logger, tables data.Tables, index map[*data.Table]int, comp []int, placed []bool, blocking func(int) []*data.ForeignKey, broken map[*data.ForeignKey]bool) int {
+	first := -1
+	for x := range tables {
+		if placed[x] || comp[x] == -1 {
+			continue
+		}
+		fks := blocking(x)
+		inCycle, breakable := true, true
+		for _, fk := range fks {
+			if comp[index[fk.RefTable]] != comp[x] {
+				inCycle = false
+			}
+			if !fk.Nullable && !fk.IsDeferrable {
+				breakable = false
+			}
+		}
+		if !inCycle {
+			continue
+		}
+		if breakable {
+			for _, fk := range fks {
+				broken[fk] = true
+			}
+			return x
+		}
+		if first == -1 {
+			first = x
+		}
+	}
+
+	// there's no way to insert rows in the cycle without violating a foreign
+	// key, so we break it anyway to put the tables in some order.
+	for _, fk := range blocking(first) {
+		log.Printf("Foreign key %v of table %v.%v is in a cycle but is neither nullable nor deferrable; ignoring it to order tables", fk.DBName, fk.Table.Schema.DBName, fk.TableDBName)
+		broken[fk] = true
+	}
+	return first
+}
+
+// components returns the strongly connected component of each table in the
+// graph of foreign keys, found with Tarjan's algorithm.  Tables in components
+// of more than one table are in a cycle, and get the component's number;
+// other tables get -1.
+func components(tables data.Tables, index map[*data.Table]int, deps [][]*data.ForeignKey) []int {
+	comp := make([]int, len(tables))
+	num := make([]int, len(tables))
+	low := make([]int, len(tables))
+	onStack := make([]bool, len(tables))
+	var stack []int
+	count, next := 0, 1
+
+	var visit func(x int)
+	visit = func(x int) {
+		num[x], low[x] = next, next
+		next++
+		stack = append(stack, x)
+		onStack[x] = true
+		for _, fk := range deps[x] {
+			y := index[fk.RefTable]
+			if num[y] == 0 {
+				visit(y)
+				if low[y] < low[x] {
+					low[x] = low[y]
+				}
+			} else if onStack[y] && num[y] < low[x] {
+				low[x] = num[y]
+			}
+		}
+		if low[x] != num[x] {
+			return
+		}
+		var members []int
+		for {
+			y := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[y] = false
+			members = append(members, y)
+			if y == x {
+				break
+			}
+		}
+		for _, y := range members {
+			comp[y] = -1
+			if len(members) > 1 {
+				comp[y] = count
+			}
+		}
+		if len(members) > 1 {
+			count++
+		}
+	}
+	for x := range tables {
+		if num[x] == 0 {
+			visit(x)
+		}
+	}
+	return comp
+}
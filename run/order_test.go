@@ -0,0 +1,115 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+)
+
+func nullable(c *database.Column) *database.Column {
+	c.Nullable = true
+	return c
+}
+
+func TestMakeDataOrderedTables(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+	}
+	deferrable := fkColumn("a_id", "b", "a")
+	deferrable.ForeignKey.IsDeferrable = true
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name:    "comments",
+				Columns: []*database.Column{idColumn(), fkColumn("post_id", "comments", "posts")},
+			}, {
+				Name:    "logs",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name:    "posts",
+				Columns: []*database.Column{idColumn(), fkColumn("user_id", "posts", "users")},
+			}, {
+				Name:    "categories",
+				Columns: []*database.Column{idColumn(), nullable(fkColumn("parent_id", "categories", "categories"))},
+			}, {
+				Name:    "users",
+				Columns: []*database.Column{idColumn(), nullable(fkColumn("best_post_id", "users", "posts"))},
+			}, {
+				Name:    "a",
+				Columns: []*database.Column{idColumn(), fkColumn("b_id", "a", "b")},
+			}, {
+				Name:    "b",
+				Columns: []*database.Column{idColumn(), deferrable},
+			}, {
+				Name:    "c",
+				Columns: []*database.Column{idColumn(), fkColumn("d_id", "c", "d")},
+			}, {
+				Name:    "d",
+				Columns: []*database.Column{idColumn(), fkColumn("c_id", "d", "c")},
+			}},
+		}},
+	}
+
+	var logs bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	expected := "logs categories users posts comments b a c d"
+	if got := strings.Join(db.OrderedTables.DBNames(), " "); got != expected {
+		t.Errorf("expected tables in order %q, got %q", expected, got)
+	}
+	if got := strings.Join(db.SchemasByName["public"].OrderedTables.DBNames(), " "); got != expected {
+		t.Errorf("expected schema tables in order %q, got %q", expected, got)
+	}
+	if got := strings.Join(db.OrderedTableDBNames, " "); got != expected {
+		t.Errorf("expected table names in order %q, got %q", expected, got)
+	}
+	if got := strings.Join(db.SchemasByName["public"].OrderedTableDBNames, " "); got != expected {
+		t.Errorf("expected schema table names in order %q, got %q", expected, got)
+	}
+	reversed := "d c a b comments posts users categories logs"
+	if got := strings.Join(db.OrderedTables.Reverse().DBNames(), " "); got != reversed {
+		t.Errorf("expected reversed tables %q, got %q", reversed, got)
+	}
+
+	// categories only references itself, so it's not a cycle.
+	if len(db.Cycles) != 3 {
+		t.Fatalf("expected 3 cycles, got %d", len(db.Cycles))
+	}
+	tests := []struct {
+		tables string
+		broken string
+	}{
+		{"users posts", "users_best_post_id_fkey"},
+		{"b a", "b_a_id_fkey"},
+		{"c d", "c_d_id_fkey"},
+	}
+	for x, test := range tests {
+		cycle := db.Cycles[x]
+		if got := strings.Join(cycle.TableDBNames, " "); got != test.tables {
+			t.Errorf("cycle %d: expected tables %q, got %q", x, test.tables, got)
+		}
+		if len(cycle.ForeignKeys) != 2 {
+			t.Errorf("cycle %d: expected 2 foreign keys, got %d", x, len(cycle.ForeignKeys))
+		}
+		if got := strings.Join(cycle.BrokenForeignKeys.DBNames(), " "); got != test.broken {
+			t.Errorf("cycle %d: expected broken foreign keys %q, got %q", x, test.broken, got)
+		}
+	}
+	if len(db.BrokenForeignKeys) != 3 {
+		t.Errorf("expected 3 broken foreign keys, got %d", len(db.BrokenForeignKeys))
+	}
+	if !strings.Contains(logs.String(), "c_d_id_fkey") {
+		t.Errorf("expected breaking a cycle with no nullable or deferrable foreign key to be logged, got %q", logs.String())
+	}
+}
@@ -0,0 +1,59 @@
+package run
+
+import (
+	"sort"
+
+	"gnorm.org/gnorm/database"
+)
+
+// override returns the override of the table, or of the column if column
+// isn't empty.  It's the zero Override if there is none.
+func (c *Config) override(schema, table, column string) Override {
+	return c.Overrides[OverrideKey{Schema: schema, Table: table, Column: column}]
+}
+
+// typeIn returns the override's type in the named type map, or in the default
+// maps if the name is empty.
+func (o Override) typeIn(typeMap string) string {
+	if typ, ok := o.Types[typeMap]; ok && typeMap != "" {
+		return typ
+	}
+	return o.Type
+}
+
+// checkOverrides reports overrides that don't match any table or column
+// that was read, which are usually typos.
+func checkOverrides(probs *problems, cfg *Config, info *database.Info) {
+	found := make(map[OverrideKey]bool, len(cfg.Overrides))
+	for _, s := range info.Schemas {
+		for _, t := range s.Tables {
+			found[OverrideKey{Schema: s.Name, Table: t.Name}] = true
+			for _, c := range t.Columns {
+				found[OverrideKey{Schema: s.Name, Table: t.Name, Column: c.Name}] = true
+			}
+		}
+	}
+	var missing []OverrideKey
+	for key := range cfg.Overrides {
+		if !found[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		a, b := missing[i], missing[j]
+		if a.Schema != b.Schema {
+			return a.Schema < b.Schema
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Column < b.Column
+	})
+	for _, key := range missing {
+		if key.Column == "" {
+			probs.add("Override for table %v.%v matches no table", key.Schema, key.Table)
+		} else {
+			probs.add("Override for column %v.%v.%v matches no column", key.Schema, key.Table, key.Column)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+func TestMakeDataOverrides(t *testing.T) {
+	t.Parallel()
+
+	notNull := false
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{pascal .}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap:         map[string]string{"text": "string", "integer": "int"},
+			NullableTypeMap: map[string]string{"text": "sql.NullString", "integer": "sql.NullInt64"},
+		},
+		Overrides: map[OverrideKey]Override{
+			{Schema: "public", Table: "users"}:                        {Name: "Account", Params: map[string]interface{}{"audit": true}},
+			{Schema: "public", Table: "users", Column: "email"}:       {Type: "Email", Params: map[string]interface{}{"validate": "email"}},
+			{Schema: "public", Table: "users", Column: "password"}:    {Exclude: true},
+			{Schema: "public", Table: "active_users", Column: "id"}:   {PrimaryKey: true, Nullable: &notNull},
+			{Schema: "public", Table: "active_users", Column: "name"}: {Name: "DisplayName"},
+			{Schema: "public", Table: "users", Column: "no_such_col"}: {Name: "Nothing"},
+		},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "users",
+				Columns: []*database.Column{
+					idColumn(),
+					{Name: "email", Type: "text", Nullable: true},
+					{Name: "password", Type: "text"},
+				},
+				Indexes: []*database.Index{{
+					Name:     "users_email_key",
+					IsUnique: true,
+					Columns:  []*database.Column{{Name: "email"}},
+				}, {
+					Name:    "users_email_password_idx",
+					Columns: []*database.Column{{Name: "email"}, {Name: "password"}},
+				}},
+			}, {
+				Name:   "active_users",
+				IsView: true,
+				Columns: []*database.Column{
+					{Name: "id", Type: "integer", Nullable: true},
+					{Name: "name", Type: "text", Nullable: true},
+				},
+			}},
+		}},
+	}
+
+	var logs bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	sch := db.SchemasByName["public"]
+
+	users := sch.TablesByName["users"]
+	if users.Name != "Account" || users.Params["audit"] != true {
+		t.Errorf("expected users to be named Account with the audit param, got %q and %v", users.Name, users.Params)
+	}
+	if got := strings.Join(users.Columns.DBNames(), " "); got != "id email" {
+		t.Errorf("expected the password column to be excluded, got columns %q", got)
+	}
+	if got := strings.Join(users.Indexes.DBNames(), " "); got != "users_email_key" {
+		t.Errorf("expected the index on the password column to be excluded, got indexes %q", got)
+	}
+	email := users.ColumnsByName["email"]
+	if email.Name != "Email" || email.Type != "Email" || email.Params["validate"] != "email" {
+		t.Errorf("expected email to have type Email and the validate param, got %q, %q and %v", email.Name, email.Type, email.Params)
+	}
+
+	view := sch.TablesByName["active_users"]
+	id := view.ColumnsByName["id"]
+	if !id.IsPrimaryKey || id.Nullable || id.Type != "int" {
+		t.Errorf("expected view column id to be a non-nullable primary key of type int, got %v, %v and %q", id.IsPrimaryKey, id.Nullable, id.Type)
+	}
+	if got := strings.Join(view.PrimaryKeys.DBNames(), " "); got != "id" {
+		t.Errorf("expected view primary keys %q, got %q", "id", got)
+	}
+	if name := view.ColumnsByName["name"]; name.Name != "DisplayName" || name.Type != "sql.NullString" {
+		t.Errorf("expected view column name to be named DisplayName with type sql.NullString, got %q and %q", name.Name, name.Type)
+	}
+	if !strings.Contains(logs.String(), "public.users.no_such_col matches no column") {
+		t.Errorf("expected an unmatched override to be logged, got %q", logs.String())
+	}
+}
+
+func TestMakeDataOverridesExcludeReferenced(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{"integer": "int", "enum": "string"},
+		},
+		Strict: true,
+		Overrides: map[OverrideKey]Override{
+			{Schema: "public", Table: "orgs", Column: "legacy_id"}:  {Exclude: true},
+			{Schema: "public", Table: "members", Column: "status"}:  {Exclude: true},
+			{Schema: "public", Table: "members", Column: "team_id"}: {Exclude: true},
+		},
+	}
+	// members has a single column foreign key to orgs.legacy_id, which is
+	// excluded, and a two column foreign key to teams whose team_id column is
+	// excluded.
+	teamFK := func(name, ref string) *database.Column {
+		return &database.Column{
+			Name:         name,
+			Type:         "integer",
+			IsForeignKey: true,
+			ForeignKey: &database.ForeignKey{
+				SchemaName:        "public",
+				TableName:         "members",
+				ColumnName:        name,
+				Name:              "members_team_fkey",
+				ForeignTableName:  "teams",
+				ForeignColumnName: ref,
+			},
+		}
+	}
+	orgFK := fkColumn("org_no", "members", "orgs")
+	orgFK.ForeignKey.ForeignColumnName = "legacy_id"
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Enums: []*database.Enum{{
+				Name:    "status",
+				Table:   "members",
+				Values:  []*database.EnumValue{{Name: "active", Value: 1}},
+				Columns: []*database.ColumnRef{{Schema: "public", Table: "members", Column: "status"}},
+			}},
+			Tables: []*database.Table{{
+				Name:    "orgs",
+				Columns: []*database.Column{idColumn(), {Name: "legacy_id", Type: "integer"}},
+			}, {
+				Name: "teams",
+				Columns: []*database.Column{
+					{Name: "org_id", Type: "integer", IsPrimaryKey: true},
+					{Name: "id", Type: "integer", IsPrimaryKey: true},
+				},
+			}, {
+				Name: "members",
+				Columns: []*database.Column{
+					idColumn(),
+					orgFK,
+					teamFK("team_org_id", "org_id"),
+					teamFK("team_id", "id"),
+					{Name: "status", Type: "enum", UserDefined: true},
+				},
+			}},
+		}},
+	}
+
+	var logs bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("expected excluded columns to cause no problems in strict mode, got %s", err)
+	}
+	sch := db.SchemasByName["public"]
+	members := sch.TablesByName["members"]
+	if len(members.ForeignKeys) != 0 {
+		t.Errorf("expected the foreign keys with excluded columns to be left out, got %v", members.ForeignKeys.DBNames())
+	}
+	if col := members.ColumnsByName["org_no"]; col.IsFK || col.FKColumn != nil {
+		t.Error("expected a column referencing an excluded column not to be a foreign key")
+	}
+	if col := members.ColumnsByName["team_org_id"]; col.IsFK || col.FKColumn != nil {
+		t.Error("expected the remaining column of a partly excluded foreign key not to be a foreign key")
+	}
+	if teams := sch.TablesByName["teams"]; len(teams.ForeignKeyRefs) != 0 || teams.ColumnsByName["org_id"].HasFKRef {
+		t.Error("expected no references to teams from a partly excluded foreign key")
+	}
+	if enum := sch.EnumsByName["status"]; len(enum.Columns) != 0 {
+		t.Errorf("expected an excluded enum column not to be linked, got %v", enum.Columns.DBNames())
+	}
+}
@@ -1,5 +1,26 @@
 package run
 
+import (
+	"context"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+)
+
+// parseDB reads the database schema described by the config.
+func parseDB(ctx context.Context, env environ.Values, cfg *Config) (*database.Info, error) {
+	return cfg.Driver.Parse(ctx, env.Log, cfg.ConnStr, database.Options{
+		Schemas:          cfg.Schemas,
+		FilterTables:     makeFilter(cfg.IncludeTables, cfg.ExcludeTables),
+		IncludeTables:    cfg.IncludeTables,
+		ExcludeTables:    cfg.ExcludeTables,
+		ConnectTimeout:   cfg.ConnectTimeout,
+		StatementTimeout: cfg.StatementTimeout,
+		Snapshot:         cfg.Snapshot,
+		Parallelism:      cfg.Parallelism,
+	})
+}
+
 func makeFilter(include, exclude map[string][]string) func(schema, table string) bool {
 	if sumLens(include) == 0 && sumLens(exclude) == 0 {
 		return func(_, _ string) bool { return true }
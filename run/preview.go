@@ -2,6 +2,7 @@ package run
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -26,6 +27,14 @@ Schema: {{.Name}}({{.DBName}})
 Enum: {{.Name}}({{$schema}}.{{.DBName}})
 {{makeTable .Values "{{.Name}}|{{.DBName}}|{{.Value}}" "Name" "DBName" "Value" }}
 {{end -}}
+{{if .Domains}}
+Domains:
+{{makeTable .Domains "{{.Name}}|{{.DBName}}|{{.Type}}|{{.BaseType}}|{{.Nullable}}|{{.Default}}|{{join .Constraints.DBNames \", \"}}" "Name" "DBName" "Type" "BaseType" "Nullable" "Default" "Constraints"}}
+{{end -}}
+{{range .CompositeTypes}}
+Composite Type: {{.Name}}({{$schema}}.{{.DBName}})
+{{makeTable .Attributes "{{.Name}}|{{.DBName}}|{{.Type}}|{{.DBType}}|{{.IsArray}}|{{.Length}}|{{.UserDefined}}|{{.Nullable}}" "Name" "DBName" "Type" "DBType" "IsArray" "Length" "UserDefined" "Nullable"}}
+{{end -}}
 {{range .Tables}}
 Table: {{.Name}}({{$schema}}.{{.DBName}}){{if ne .Comment ""}}; {{.Comment}}{{end}}
 {{makeTable .Columns "{{.Name}}|{{.DBName}}|{{.Type}}|{{.DBType}}|{{.IsArray}}|{{.IsPrimaryKey}}|{{.Ordinal}}|{{.IsFK}}|{{.HasFKRef}}|{{.Length}}|{{.UserDefined}}|{{.Nullable}}|{{.HasDefault}}|{{.Comment}}" "Name" "DBName" "Type" "DBType" "IsArray" "IsPrimaryKey" "Ordinal" "IsFK" "HasFKRef" "Length" "UserDefined" "Nullable" "HasDefault" "Comment" -}}
@@ -50,19 +59,20 @@ const (
 )
 
 // Preview displays the database info that would be passed to your template
-// based on your configuration.
-func Preview(env environ.Values, cfg *Config, format PreviewFormat) error {
-	info, err := cfg.Driver.Parse(env.Log, cfg.ConnStr, cfg.Schemas, makeFilter(cfg.IncludeTables, cfg.ExcludeTables))
+// based on your configuration.  Reading the database stops with an error if
+// the context is cancelled.
+func Preview(ctx context.Context, env environ.Values, cfg *Config, format PreviewFormat) error {
+	info, err := parseDB(ctx, env, cfg)
 	if err != nil {
 		return err
 	}
-	data, err := makeData(env.Log, info, cfg)
+	data, err := makeData(env, info, cfg)
 	if err != nil {
 		return err
 	}
 	switch format {
 	case PreviewTypes:
-		displayTypes(env, data)
+		displayTypes(env, cfg, data)
 		return nil
 	case PreviewYAML:
 		b, err := yaml.Marshal(data)
@@ -129,43 +139,104 @@ func makeTable(data interface{}, templateStr string, columnTitles ...string) (st
 	return output.String(), nil
 }
 
-func displayTypes(env environ.Values, info *data.DBData) {
-	var nullCols []*data.Column
-	var cols []*data.Column
+// typeEntry is a line of the types preview.
+type typeEntry struct {
+	key string
+	typ string
+}
+
+// displayTypes prints the types of the columns as type maps.  Columns whose
+// type was mapped by a type rule are listed after them, with the rule that
+// applied, and so are columns whose type was set by an override.
+func displayTypes(env environ.Values, cfg *Config, info *data.DBData) {
+	var nullCols []typeEntry
+	var cols []typeEntry
+	var ruleCols, overrideCols []string
 	lookUp := make(map[string]bool)
 	nullLookUp := make(map[string]bool)
+	rules := make(map[string]bool, len(cfg.TypeRules))
+	for _, r := range cfg.TypeRules {
+		rules[r.Name] = true
+	}
 	for _, v := range info.Schemas {
 		for _, t := range v.Tables {
 			for _, c := range t.Columns {
+				if rules[c.TypeSource] {
+					ruleCols = append(ruleCols, fmt.Sprintf("%s.%s.%s %s = %q (%s)", v.DBName, t.DBName, c.DBName, c.DBType, c.Type, c.TypeSource))
+					continue
+				}
+				if c.TypeSource == "Overrides" {
+					overrideCols = append(overrideCols, fmt.Sprintf("%s.%s.%s %s = %q", v.DBName, t.DBName, c.DBName, c.DBType, c.Type))
+					continue
+				}
+				e := typeEntry{key: previewTypeKey(cfg, c), typ: c.Type}
 				if c.Nullable {
-					if !nullLookUp[c.DBType] {
-						nullCols = append(nullCols, c)
-						nullLookUp[c.DBType] = true
+					if !nullLookUp[e.key] {
+						nullCols = append(nullCols, e)
+						nullLookUp[e.key] = true
 					}
 				} else {
-					if !lookUp[c.DBType] {
-						cols = append(cols, c)
-						lookUp[c.DBType] = true
+					if !lookUp[e.key] {
+						cols = append(cols, e)
+						lookUp[e.key] = true
 					}
 				}
 			}
 		}
 	}
 	sort.SliceStable(cols, func(i, j int) bool {
-		return cols[i].DBType < cols[j].DBType
+		return cols[i].key < cols[j].key
 	})
 	sort.SliceStable(nullCols, func(i, j int) bool {
-		return nullCols[i].DBType < nullCols[j].DBType
+		return nullCols[i].key < nullCols[j].key
 	})
 
 	fmt.Fprintln(env.Stdout, "[TypeMap]")
-	for _, c := range cols {
-		fmt.Fprintf(env.Stdout, "%q = %q\n", c.DBType, c.Type)
+	for _, e := range cols {
+		fmt.Fprintf(env.Stdout, "%q = %q\n", e.key, e.typ)
 	}
 	fmt.Fprintln(env.Stdout)
 
 	fmt.Fprintln(env.Stdout, "[NullableTypeMap]")
-	for _, c := range nullCols {
-		fmt.Fprintf(env.Stdout, "%q = %q\n", c.DBType, c.Type)
+	for _, e := range nullCols {
+		fmt.Fprintf(env.Stdout, "%q = %q\n", e.key, e.typ)
+	}
+
+	if len(ruleCols) > 0 {
+		fmt.Fprintln(env.Stdout)
+		fmt.Fprintln(env.Stdout, "# Columns mapped by TypeRules:")
+		for _, line := range ruleCols {
+			fmt.Fprintln(env.Stdout, "#", line)
+		}
+	}
+
+	if len(overrideCols) > 0 {
+		fmt.Fprintln(env.Stdout)
+		fmt.Fprintln(env.Stdout, "# Columns mapped by Overrides:")
+		for _, line := range overrideCols {
+			fmt.Fprintln(env.Stdout, "#", line)
+		}
+	}
+}
+
+// previewTypeKey returns the type map key the column's type was mapped with,
+// as columnType found it, including the shape of arrays.  Unmapped columns
+// get their database type, or the unsigned variant of it for unsigned
+// columns, since those most likely need a different type.
+func previewTypeKey(cfg *Config, c *data.Column) string {
+	ov := cfg.override(c.Table.Schema.DBName, c.Table.DBName, c.DBName)
+	// the type was already mapped when the data was made, so this can't fail
+	// and we drop the error.
+	_, _, key, ok, _ := columnType(cfg, c, ov)
+	if ok {
+		return key
+	}
+	key = c.DBType
+	if c.Unsigned {
+		key += " unsigned"
+	}
+	if c.IsArray {
+		key += strings.Repeat("[]", arrayDimensions(c))
 	}
+	return key
 }
@@ -2,6 +2,7 @@ package run
 
 import (
 	"bytes"
+	"context"
 	"log"
 	"testing"
 	"text/template"
@@ -47,13 +48,14 @@ func TestMakeTable(t *testing.T) {
 
 type dummyDriver struct{}
 
-func (dummyDriver) Parse(log *log.Logger, conn string, schemaNames []string, filterTables func(schema, table string) bool) (*database.Info, error) {
+func (dummyDriver) Parse(ctx context.Context, log *log.Logger, conn string, opts database.Options) (*database.Info, error) {
 	return &database.Info{
 		Schemas: []*database.Schema{{
 			Name: "schema",
 			Tables: []*database.Table{{
 				Name:         "table",
 				Type:         "BASE TABLE",
+				Kind:         database.KindTable,
 				IsView:       false,
 				IsInsertable: true,
 				Comment:      "a table",
@@ -88,6 +90,7 @@ func (dummyDriver) Parse(log *log.Logger, conn string, schemaNames []string, fil
 			}, {
 				Name:         "tb2",
 				Type:         "VIEW",
+				Kind:         database.KindView,
 				IsView:       true,
 				IsInsertable: false,
 				Columns: []*database.Column{{
@@ -125,20 +128,46 @@ const expectYaml = `schemas:
   - name: abc table
     dbname: table
     type: BASE TABLE
+    kind: table
     isview: false
     isinsertable: true
     comment: a table
+    description: a table
+    annotations: {}
+    partitionkey: ""
+    partitionbound: ""
+    viewdefinition: ""
     columns:
     - name: abc col1
       dbname: col1
       type: INTEGER
       dbtype: int
+      typesource: TypeMap["int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: first column
+      description: first column
+      annotations: {}
       isprimarykey: true
       ordinal: 123456
       isfk: false
@@ -148,65 +177,149 @@ const expectYaml = `schemas:
       - dbname: tb2_col2_fkey
         columndbname: col2
         refcolumndbname: col1
+      params: {}
     - name: abc col2
       dbname: col2
       type: '*INTEGER'
       dbtype: '*int'
+      typesource: NullableTypeMap["*int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: true
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: false
       ordinal: 0
       isfk: false
       hasfkref: false
       fkcolumn: null
       fkcolumnrefs: []
+      params: {}
     - name: abc col3
       dbname: col3
       type: ""
       dbtype: string
+      typesource: ""
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: false
       ordinal: 0
       isfk: false
       hasfkref: false
       fkcolumn: null
       fkcolumnrefs: []
+      params: {}
     - name: abc col4
       dbname: col4
       type: ""
       dbtype: '*string'
+      typesource: ""
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: true
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: false
       ordinal: 0
       isfk: false
       hasfkref: false
       fkcolumn: null
       fkcolumnrefs: []
+      params: {}
     primarykeys:
     - name: abc col1
       dbname: col1
       type: INTEGER
       dbtype: int
+      typesource: TypeMap["int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: first column
+      description: first column
+      annotations: {}
       isprimarykey: true
       ordinal: 123456
       isfk: false
@@ -216,6 +329,7 @@ const expectYaml = `schemas:
       - dbname: tb2_col2_fkey
         columndbname: col2
         refcolumndbname: col1
+      params: {}
     indexes:
     - name: abc col1_pkey
       dbname: col1_pkey
@@ -225,12 +339,32 @@ const expectYaml = `schemas:
         dbname: col1
         type: INTEGER
         dbtype: int
+        typesource: TypeMap["int"]
+        types: {}
         isarray: false
+        arraydimensions: 0
+        isrange: false
+        ismultirange: false
+        rangesubtype: ""
+        extension: ""
+        geometrytype: ""
+        srid: 0
         length: 0
+        precision: 0
+        scale: 0
+        datetimeprecision: 0
+        characterset: ""
+        collation: ""
+        columntype: ""
+        unsigned: false
+        zerofill: false
         userdefined: false
+        typeschema: ""
         nullable: false
         hasdefault: false
         comment: first column
+        description: first column
+        annotations: {}
         isprimarykey: true
         ordinal: 123456
         isfk: false
@@ -240,49 +374,125 @@ const expectYaml = `schemas:
         - dbname: tb2_col2_fkey
           columndbname: col2
           refcolumndbname: col1
+        params: {}
+      comment: ""
+      description: ""
+      annotations: {}
+    triggers: []
     foreignkeys: []
     foreignkeyrefs:
     - dbname: tb2_col2_fkey
       name: abc tb2_col2_fkey
       tabledbname: tb2
+      refschemadbname: schema
       reftabledbname: table
       fkcolumns:
       - dbname: tb2_col2_fkey
         columndbname: col2
         refcolumndbname: col1
+      nullable: false
+      isdeferrable: false
+      initiallydeferred: false
+      comment: ""
+      description: ""
+      annotations: {}
+    relations:
+    - name: abc tb2
+      dbname: tb2
+      kind: has-many
+      refschemadbname: schema
+      reftabledbname: tb2
+      throughdbname: ""
+      path:
+      - tabledbname: table
+        columndbnames:
+        - col1
+        reftabledbname: tb2
+        refcolumndbnames:
+        - col2
+    isjointable: false
+    params: {}
+    imports: []
   - name: abc tb2
     dbname: tb2
     type: VIEW
+    kind: view
     isview: true
     isinsertable: false
     comment: ""
+    description: ""
+    annotations: {}
+    partitionkey: ""
+    partitionbound: ""
+    viewdefinition: ""
     columns:
     - name: abc col1
       dbname: col1
       type: INTEGER
       dbtype: int
+      typesource: TypeMap["int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: true
       ordinal: 0
       isfk: false
       hasfkref: false
       fkcolumn: null
       fkcolumnrefs: []
+      params: {}
     - name: abc col2
       dbname: col2
       type: INTEGER
       dbtype: int
+      typesource: TypeMap["int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: false
       ordinal: 0
       isfk: true
@@ -292,41 +502,104 @@ const expectYaml = `schemas:
         columndbname: col2
         refcolumndbname: col1
       fkcolumnrefs: []
+      params: {}
     primarykeys:
     - name: abc col1
       dbname: col1
       type: INTEGER
       dbtype: int
+      typesource: TypeMap["int"]
+      types: {}
       isarray: false
+      arraydimensions: 0
+      isrange: false
+      ismultirange: false
+      rangesubtype: ""
+      extension: ""
+      geometrytype: ""
+      srid: 0
       length: 0
+      precision: 0
+      scale: 0
+      datetimeprecision: 0
+      characterset: ""
+      collation: ""
+      columntype: ""
+      unsigned: false
+      zerofill: false
       userdefined: false
+      typeschema: ""
       nullable: false
       hasdefault: false
       comment: ""
+      description: ""
+      annotations: {}
       isprimarykey: true
       ordinal: 0
       isfk: false
       hasfkref: false
       fkcolumn: null
       fkcolumnrefs: []
+      params: {}
     indexes: []
+    triggers: []
     foreignkeys:
     - dbname: tb2_col2_fkey
       name: abc tb2_col2_fkey
       tabledbname: tb2
+      refschemadbname: schema
       reftabledbname: table
       fkcolumns:
       - dbname: tb2_col2_fkey
         columndbname: col2
         refcolumndbname: col1
+      nullable: false
+      isdeferrable: false
+      initiallydeferred: false
+      comment: ""
+      description: ""
+      annotations: {}
     foreignkeyrefs: []
+    relations:
+    - name: abc table
+      dbname: table
+      kind: belongs-to
+      refschemadbname: schema
+      reftabledbname: table
+      throughdbname: ""
+      path:
+      - tabledbname: tb2
+        columndbnames:
+        - col2
+        reftabledbname: table
+        refcolumndbnames:
+        - col1
+    isjointable: false
+    params: {}
+    imports: []
   enums:
   - name: abc enum
     dbname: enum
+    isset: false
     values:
     - name: abc enumvalue
       dbname: enumvalue
       value: 0
+    comment: ""
+    description: ""
+    annotations: {}
+  compositetypes: []
+  domains: []
+  sequences: []
+  orderedtabledbnames:
+  - table
+  - tb2
+  imports: []
+orderedtabledbnames:
+- table
+- tb2
+cycles: []
+brokenforeignkeys: []
 `
 
 const expectTabular = `Schema: abc schema(schema)
@@ -392,7 +665,7 @@ func TestPreviewYAML(t *testing.T) {
 		Driver: dummyDriver{},
 	}
 	// with yaml
-	if err := Preview(env, cfg, PreviewYAML); err != nil {
+	if err := Preview(context.Background(), env, cfg, PreviewYAML); err != nil {
 		t.Fatal(err)
 	}
 	v := out.String()
@@ -412,21 +685,47 @@ var expectJSON = `
           "Name": "abc table",
           "DBName": "table",
           "Type": "BASE TABLE",
+          "Kind": "table",
           "IsView": false,
           "IsInsertable": true,
           "Comment": "a table",
+          "Description": "a table",
+          "Annotations": null,
+          "PartitionKey": "",
+          "PartitionBound": "",
+          "ViewDefinition": "",
           "Columns": [
             {
               "Name": "abc col1",
               "DBName": "col1",
               "Type": "INTEGER",
               "DBType": "int",
+              "TypeSource": "TypeMap[\"int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "first column",
+              "Description": "first column",
+              "Annotations": null,
               "IsPrimaryKey": true,
               "Ordinal": 123456,
               "IsFK": false,
@@ -438,61 +737,125 @@ var expectJSON = `
                   "ColumnDBName": "col2",
                   "RefColumnDBName": "col1"
                 }
-              ]
+              ],
+              "Params": null
             },
             {
               "Name": "abc col2",
               "DBName": "col2",
               "Type": "*INTEGER",
               "DBType": "*int",
+              "TypeSource": "NullableTypeMap[\"*int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": true,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": false,
               "Ordinal": 0,
               "IsFK": false,
               "HasFKRef": false,
               "FKColumn": null,
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             },
             {
               "Name": "abc col3",
               "DBName": "col3",
               "Type": "",
               "DBType": "string",
+              "TypeSource": "",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": false,
               "Ordinal": 0,
               "IsFK": false,
               "HasFKRef": false,
               "FKColumn": null,
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             },
             {
               "Name": "abc col4",
               "DBName": "col4",
               "Type": "",
               "DBType": "*string",
+              "TypeSource": "",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": true,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": false,
               "Ordinal": 0,
               "IsFK": false,
               "HasFKRef": false,
               "FKColumn": null,
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             }
           ],
           "PrimaryKeys": [
@@ -501,12 +864,32 @@ var expectJSON = `
               "DBName": "col1",
               "Type": "INTEGER",
               "DBType": "int",
+              "TypeSource": "TypeMap[\"int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "first column",
+              "Description": "first column",
+              "Annotations": null,
               "IsPrimaryKey": true,
               "Ordinal": 123456,
               "IsFK": false,
@@ -518,7 +901,8 @@ var expectJSON = `
                   "ColumnDBName": "col2",
                   "RefColumnDBName": "col1"
                 }
-              ]
+              ],
+              "Params": null
             }
           ],
           "Indexes": [
@@ -532,12 +916,32 @@ var expectJSON = `
                   "DBName": "col1",
                   "Type": "INTEGER",
                   "DBType": "int",
+                  "TypeSource": "TypeMap[\"int\"]",
+                  "Types": null,
                   "IsArray": false,
+                  "ArrayDimensions": 0,
+                  "IsRange": false,
+                  "IsMultirange": false,
+                  "RangeSubtype": "",
+                  "Extension": "",
+                  "GeometryType": "",
+                  "SRID": 0,
                   "Length": 0,
+                  "Precision": 0,
+                  "Scale": 0,
+                  "DatetimePrecision": 0,
+                  "CharacterSet": "",
+                  "Collation": "",
+                  "ColumnType": "",
+                  "Unsigned": false,
+                  "Zerofill": false,
                   "UserDefined": false,
+                  "TypeSchema": "",
                   "Nullable": false,
                   "HasDefault": false,
                   "Comment": "first column",
+                  "Description": "first column",
+                  "Annotations": null,
                   "IsPrimaryKey": true,
                   "Ordinal": 123456,
                   "IsFK": false,
@@ -549,17 +953,23 @@ var expectJSON = `
                       "ColumnDBName": "col2",
                       "RefColumnDBName": "col1"
                     }
-                  ]
+                  ],
+                  "Params": null
                 }
-              ]
+              ],
+              "Comment": "",
+              "Description": "",
+              "Annotations": null
             }
           ],
+          "Triggers": null,
           "ForeignKeys": null,
           "ForeignKeyRefs": [
             {
               "DBName": "tb2_col2_fkey",
               "Name": "abc tb2_col2_fkey",
               "TableDBName": "tb2",
+              "RefSchemaDBName": "schema",
               "RefTableDBName": "table",
               "FKColumns": [
                 {
@@ -567,47 +977,125 @@ var expectJSON = `
                   "ColumnDBName": "col2",
                   "RefColumnDBName": "col1"
                 }
+              ],
+              "Nullable": false,
+              "IsDeferrable": false,
+              "InitiallyDeferred": false,
+              "Comment": "",
+              "Description": "",
+              "Annotations": null
+            }
+          ],
+          "Relations": [
+            {
+              "Name": "abc tb2",
+              "DBName": "tb2",
+              "Kind": "has-many",
+              "RefSchemaDBName": "schema",
+              "RefTableDBName": "tb2",
+              "ThroughDBName": "",
+              "Path": [
+                {
+                  "TableDBName": "table",
+                  "ColumnDBNames": [
+                    "col1"
+                  ],
+                  "RefTableDBName": "tb2",
+                  "RefColumnDBNames": [
+                    "col2"
+                  ]
+                }
               ]
             }
-          ]
+          ],
+          "IsJoinTable": false,
+          "Params": null,
+          "Imports": null
         },
         {
           "Name": "abc tb2",
           "DBName": "tb2",
           "Type": "VIEW",
+          "Kind": "view",
           "IsView": true,
           "IsInsertable": false,
           "Comment": "",
+          "Description": "",
+          "Annotations": null,
+          "PartitionKey": "",
+          "PartitionBound": "",
+          "ViewDefinition": "",
           "Columns": [
             {
               "Name": "abc col1",
               "DBName": "col1",
               "Type": "INTEGER",
               "DBType": "int",
+              "TypeSource": "TypeMap[\"int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": true,
               "Ordinal": 0,
               "IsFK": false,
               "HasFKRef": false,
               "FKColumn": null,
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             },
             {
               "Name": "abc col2",
               "DBName": "col2",
               "Type": "INTEGER",
               "DBType": "int",
+              "TypeSource": "TypeMap[\"int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": false,
               "Ordinal": 0,
               "IsFK": true,
@@ -617,7 +1105,8 @@ var expectJSON = `
                 "ColumnDBName": "col2",
                 "RefColumnDBName": "col1"
               },
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             }
           ],
           "PrimaryKeys": [
@@ -626,26 +1115,49 @@ var expectJSON = `
               "DBName": "col1",
               "Type": "INTEGER",
               "DBType": "int",
+              "TypeSource": "TypeMap[\"int\"]",
+              "Types": null,
               "IsArray": false,
+              "ArrayDimensions": 0,
+              "IsRange": false,
+              "IsMultirange": false,
+              "RangeSubtype": "",
+              "Extension": "",
+              "GeometryType": "",
+              "SRID": 0,
               "Length": 0,
+              "Precision": 0,
+              "Scale": 0,
+              "DatetimePrecision": 0,
+              "CharacterSet": "",
+              "Collation": "",
+              "ColumnType": "",
+              "Unsigned": false,
+              "Zerofill": false,
               "UserDefined": false,
+              "TypeSchema": "",
               "Nullable": false,
               "HasDefault": false,
               "Comment": "",
+              "Description": "",
+              "Annotations": null,
               "IsPrimaryKey": true,
               "Ordinal": 0,
               "IsFK": false,
               "HasFKRef": false,
               "FKColumn": null,
-              "FKColumnRefs": null
+              "FKColumnRefs": null,
+              "Params": null
             }
           ],
           "Indexes": null,
+          "Triggers": null,
           "ForeignKeys": [
             {
               "DBName": "tb2_col2_fkey",
               "Name": "abc tb2_col2_fkey",
               "TableDBName": "tb2",
+              "RefSchemaDBName": "schema",
               "RefTableDBName": "table",
               "FKColumns": [
                 {
@@ -653,27 +1165,76 @@ var expectJSON = `
                   "ColumnDBName": "col2",
                   "RefColumnDBName": "col1"
                 }
+              ],
+              "Nullable": false,
+              "IsDeferrable": false,
+              "InitiallyDeferred": false,
+              "Comment": "",
+              "Description": "",
+              "Annotations": null
+            }
+          ],
+          "ForeignKeyRefs": null,
+          "Relations": [
+            {
+              "Name": "abc table",
+              "DBName": "table",
+              "Kind": "belongs-to",
+              "RefSchemaDBName": "schema",
+              "RefTableDBName": "table",
+              "ThroughDBName": "",
+              "Path": [
+                {
+                  "TableDBName": "tb2",
+                  "ColumnDBNames": [
+                    "col2"
+                  ],
+                  "RefTableDBName": "table",
+                  "RefColumnDBNames": [
+                    "col1"
+                  ]
+                }
               ]
             }
           ],
-          "ForeignKeyRefs": null
+          "IsJoinTable": false,
+          "Params": null,
+          "Imports": null
         }
       ],
       "Enums": [
         {
           "Name": "abc enum",
           "DBName": "enum",
+          "IsSet": false,
           "Values": [
             {
               "Name": "abc enumvalue",
               "DBName": "enumvalue",
               "Value": 0
             }
-          ]
+          ],
+          "Comment": "",
+          "Description": "",
+          "Annotations": null
         }
-      ]
+      ],
+      "CompositeTypes": null,
+      "Domains": null,
+      "Sequences": null,
+      "OrderedTableDBNames": [
+        "table",
+        "tb2"
+      ],
+      "Imports": null
     }
-  ]
+  ],
+  "OrderedTableDBNames": [
+    "table",
+    "tb2"
+  ],
+  "Cycles": null,
+  "BrokenForeignKeys": null
 }`[1:]
 
 func TestPreviewJSON(t *testing.T) {
@@ -697,7 +1258,7 @@ func TestPreviewJSON(t *testing.T) {
 		Driver: dummyDriver{},
 	}
 	// with json
-	if err := Preview(env, cfg, PreviewJSON); err != nil {
+	if err := Preview(context.Background(), env, cfg, PreviewJSON); err != nil {
 		t.Fatal(err)
 	}
 	v := out.String()
@@ -728,7 +1289,7 @@ func TestPreviewTabular(t *testing.T) {
 	}
 
 	// tabular
-	if err := Preview(env, cfg, PreviewTabular); err != nil {
+	if err := Preview(context.Background(), env, cfg, PreviewTabular); err != nil {
 		t.Fatal(err)
 	}
 
@@ -768,7 +1329,7 @@ func TestPreviewTypes(t *testing.T) {
 		},
 		Driver: dummyDriver{},
 	}
-	if err := Preview(env, cfg, PreviewTypes); err != nil {
+	if err := Preview(context.Background(), env, cfg, PreviewTypes); err != nil {
 		t.Fatal(err)
 	}
 	v := out.String()
@@ -776,3 +1337,52 @@ func TestPreviewTypes(t *testing.T) {
 		t.Errorf("expected %s got %s", typesOut, v)
 	}
 }
+
+func TestPreviewTypesKeys(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{"int4": "int32", "int4[]": "pq.Int32Array", "text": "string"},
+		},
+		ArrayType: template.Must(template.New("").Parse(`[]{{.Type}}`)),
+		Overrides: map[OverrideKey]Override{
+			{Schema: "public", Table: "posts", Column: "slug"}: {Type: "Slug"},
+		},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "posts",
+				Columns: []*database.Column{
+					{Name: "id", Type: "int4"},
+					{Name: "scores", Type: "int4", IsArray: true, ArrayDimensions: 1},
+					{Name: "tags", Type: "text", IsArray: true, ArrayDimensions: 1},
+					{Name: "slug", Type: "text"},
+				},
+			}},
+		}},
+	}
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	var out bytes.Buffer
+	displayTypes(environ.Values{Stdout: &out}, c, db)
+	expected := `[TypeMap]
+"int4" = "int32"
+"int4[]" = "pq.Int32Array"
+"text[]" = "[]string"
+
+[NullableTypeMap]
+
+# Columns mapped by Overrides:
+# public.posts.slug text = "Slug"
+`
+	if out.String() != expected {
+		t.Errorf("expected types preview %s got %s", expected, out.String())
+	}
+}
@@ -0,0 +1,34 @@
+package run
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// problems collects the problems found while making the template data: types
+// that aren't mapped, references to things that weren't read, indexes that
+// were skipped and converted names that are empty, collide or are reserved.
+// Each problem is logged as it's found, and in strict mode, makeData fails
+// with all of them.
+type problems struct {
+	log  *log.Logger
+	list []string
+}
+
+// add logs a problem and records it.
+func (p *problems) add(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	p.log.Println(msg)
+	p.list = append(p.list, msg)
+}
+
+// err returns an error listing all the problems, or nil if there are none.
+func (p *problems) err() error {
+	if len(p.list) == 0 {
+		return nil
+	}
+	return errors.Errorf("strict mode found %d problems:\n\t%s", len(p.list), strings.Join(p.list, "\n\t"))
+}
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+func TestMakeDataStrict(t *testing.T) {
+	t.Parallel()
+
+	info := func() *database.Info {
+		return &database.Info{
+			Schemas: []*database.Schema{{
+				Name: "public",
+				Tables: []*database.Table{{
+					Name: "posts",
+					Columns: []*database.Column{
+						idColumn(),
+						fkColumn("user_id", "posts", "users"),
+						{Name: "body", Type: "json", Nullable: true},
+						{Name: "_", Type: "integer"},
+						{Name: "type", Type: "integer"},
+						{Name: "type_", Type: "integer"},
+					},
+					Indexes: []*database.Index{{
+						Name:    "posts_title_idx",
+						Columns: []*database.Column{{Name: "title"}},
+					}},
+				}, {
+					Name:         "posts_2020",
+					Kind:         database.KindPartition,
+					ParentSchema: "public",
+					Parent:       "posts_all",
+					Columns:      []*database.Column{idColumn()},
+				}, {
+					Name:          "post_ids",
+					Kind:          database.KindView,
+					Columns:       []*database.Column{idColumn()},
+					SourceColumns: []*database.ColumnRef{{Schema: "public", Table: "drafts", Column: "id"}},
+				}},
+			}},
+		}
+	}
+	cfg := func(strict bool) *Config {
+		return &Config{
+			NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{trim . "_"}}`)),
+			ConfigData: data.ConfigData{
+				TypeMap: map[string]string{"integer": "int"},
+			},
+			Overrides: map[OverrideKey]Override{
+				{Schema: "public", Table: "comments"}: {Name: "Comment"},
+			},
+			NameCollisions: CollisionError,
+			ReservedWords:  []string{"type"},
+			Strict:         strict,
+		}
+	}
+
+	var logs, stderr bytes.Buffer
+	lenient := cfg(false)
+	lenient.NameCollisions = CollisionWarn
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0), Stderr: &stderr}, info(), lenient)
+	if err != nil {
+		t.Fatalf("expected problems to only be logged when not strict, got %s", err)
+	}
+	if len(db.SchemasByName["public"].TablesByName["posts"].Indexes) != 0 {
+		t.Error("expected the index on an unknown column to be skipped")
+	}
+
+	_, err = makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info(), cfg(true))
+	if err == nil {
+		t.Fatal("expected an error in strict mode, but got none")
+	}
+	expected := []string{
+		"strict mode found 10 problems:",
+		"Unmapped nullable type json of column public.posts.body",
+		"Skipped index posts_title_idx of table public.posts, which references unknown column title",
+		"Unmapped foreign table public.users for foreign key posts_user_id_fkey of column public.posts.user_id",
+		"Unmapped parent table public.posts_all of public.posts_2020",
+		"Unmapped source column public.drafts.id of view public.post_ids",
+		"Override for table public.comments matches no table",
+		`Name conversion of column public.posts._ resulted in an empty name`,
+		`column public.posts.type has the name "type", which is a reserved word`,
+		`column public.posts.type_ has the name "type", which is a reserved word`,
+		`column public.posts.type and column public.posts.type_ both have the name "type"`,
+	}
+	if got := strings.Split(err.Error(), "\n\t"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected error\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	// name warnings go to stderr rather than the log.
+	for _, problem := range expected[1:8] {
+		if !strings.Contains(logs.String(), problem) {
+			t.Errorf("expected %q to be logged, got %q", problem, logs.String())
+		}
+	}
+	for _, problem := range expected[8:] {
+		if !strings.Contains(stderr.String(), problem) {
+			t.Errorf("expected a warning %q, got %q", problem, stderr.String())
+		}
+	}
+}
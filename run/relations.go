@@ -0,0 +1,288 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gnorm.org/gnorm/run/data"
+)
+
+// namedRelation is a relation with the names it may be given, from the
+// simplest to the most specific.  A relation gets the simplest name that no
+// other relation of the same table wants.
+type namedRelation struct {
+	rel   *data.Relation
+	names []string
+}
+
+// mapRelations derives the relations of every table from the foreign keys
+// between them, so it must run after the foreign keys have been mapped.
+func mapRelations(cfg *Config, db *data.DBData, convert nameConverter) error {
+	for _, sch := range db.Schemas {
+		for _, t := range sch.Tables {
+			t.IsJoinTable = isJoinTable(t)
+		}
+	}
+	for _, sch := range db.Schemas {
+		for _, t := range sch.Tables {
+			var rels []namedRelation
+			for _, fk := range t.ForeignKeys {
+				rels = append(rels, belongsTo(fk))
+			}
+			for _, fk := range t.ForeignKeyRefs {
+				rels = append(rels, hasOneOrMany(fk))
+			}
+			for _, fk := range t.ForeignKeyRefs {
+				if !fk.Table.IsJoinTable {
+					continue
+				}
+				for _, far := range fk.Table.ForeignKeys {
+					if far != fk {
+						rels = append(rels, manyToMany(fk, far))
+					}
+				}
+			}
+			if err := nameRelations(cfg, rels, convert); err != nil {
+				return errors.WithMessage(err, "relation of table "+t.DBName)
+			}
+			for _, r := range rels {
+				t.Relations = append(t.Relations, r.rel)
+			}
+		}
+	}
+	return nil
+}
+
+// isJoinTable reports whether the table only exists to link two other tables
+// (or a table to itself): it has exactly two foreign keys, whose columns
+// together are its primary key or a unique index, so each pair is linked only
+// once, and any column that isn't part of them has a default, like a serial id
+// or a created_at timestamp.
+func isJoinTable(t *data.Table) bool {
+	if t.IsView || len(t.ForeignKeys) != 2 {
+		return false
+	}
+	cols := append(fkColumns(t.ForeignKeys[0]), fkColumns(t.ForeignKeys[1])...)
+	if !isUnique(t, cols) {
+		return false
+	}
+	for _, c := range t.Columns {
+		if c.IsFK || c.HasDefault {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// belongsTo returns the relation of a foreign key's table to the table it
+// references.  It's named after the foreign key column without its id suffix
+// (e.g. author for author_id), or after the referenced table.
+func belongsTo(fk *data.ForeignKey) namedRelation {
+	return namedRelation{
+		rel: &data.Relation{
+			Kind:            data.RelationBelongsTo,
+			RefSchemaDBName: fk.RefTable.Schema.DBName,
+			RefTableDBName:  fk.RefTable.DBName,
+			Table:           fk.Table,
+			RefTable:        fk.RefTable,
+			Path:            data.RelationSteps{forwardStep(fk)},
+		},
+		names: []string{belongsToName(fk), fk.DBName},
+	}
+}
+
+// hasOneOrMany returns the relation of a table to the table whose foreign key
+// references it, which is has-one if the foreign key columns are unique.  It's
+// named after the referencing table.
+func hasOneOrMany(fk *data.ForeignKey) namedRelation {
+	kind := data.RelationHasMany
+	if isUnique(fk.Table, fkColumns(fk)) {
+		kind = data.RelationHasOne
+	}
+	return namedRelation{
+		rel: &data.Relation{
+			Kind:            kind,
+			RefSchemaDBName: fk.Table.Schema.DBName,
+			RefTableDBName:  fk.Table.DBName,
+			Table:           fk.RefTable,
+			RefTable:        fk.Table,
+			Path:            data.RelationSteps{reverseStep(fk)},
+		},
+		names: []string{
+			fk.Table.DBName,
+			belongsToName(fk) + "_" + fk.Table.DBName,
+			fk.Table.DBName + "_" + fk.DBName,
+		},
+	}
+}
+
+// manyToMany returns the relation of the table referenced by near to the table
+// referenced by far, through the join table both foreign keys belong to.  It's
+// named after the far table.
+func manyToMany(near, far *data.ForeignKey) namedRelation {
+	join := near.Table
+	return namedRelation{
+		rel: &data.Relation{
+			Kind:            data.RelationManyToMany,
+			RefSchemaDBName: far.RefTable.Schema.DBName,
+			RefTableDBName:  far.RefTable.DBName,
+			ThroughDBName:   join.DBName,
+			Table:           near.RefTable,
+			RefTable:        far.RefTable,
+			Through:         join,
+			Path:            data.RelationSteps{reverseStep(near), forwardStep(far)},
+		},
+		names: []string{
+			far.RefTable.DBName,
+			join.DBName,
+			join.DBName + "_" + belongsToName(far),
+			join.DBName + "_" + far.DBName,
+		},
+	}
+}
+
+// nameRelations gives each relation of a table its simplest name that isn't
+// wanted by any other relation, and converts it.  Relations that still clash
+// after running out of names are numbered.
+func nameRelations(cfg *Config, rels []namedRelation, convert nameConverter) error {
+	level := make([]int, len(rels))
+	for {
+		wanted := map[string]int{}
+		for x, r := range rels {
+			wanted[r.names[level[x]]]++
+		}
+		more := false
+		for x, r := range rels {
+			if wanted[r.names[level[x]]] > 1 && level[x] < len(r.names)-1 {
+				level[x]++
+				more = true
+			}
+		}
+		if !more {
+			break
+		}
+	}
+
+	seen := map[string]int{}
+	for x, r := range rels {
+		name := r.names[level[x]]
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s_%d", name, seen[name])
+		}
+		r.rel.DBName = name
+		var err error
+		r.rel.Name, err = convert(cfg.RelationNameConversion, nameData{DBName: name, Schema: r.rel.Table.Schema, Table: r.rel.Table, Relation: r.rel})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// belongsToName returns the name of the table a foreign key references, as seen
+// from the foreign key's table: the name of the foreign key column without its
+// id suffix, or if there isn't one, the name of the referenced table.
+func belongsToName(fk *data.ForeignKey) string {
+	if len(fk.FKColumns) == 1 {
+		if name := trimID(fk.FKColumns[0].ColumnDBName); name != "" {
+			return name
+		}
+	}
+	return fk.RefTable.DBName
+}
+
+// trimID returns the name without an id suffix, like author for author_id,
+// authorId or authorID.  If the name has no id suffix, it returns "".
+func trimID(name string) string {
+	if len(name) > 3 && strings.EqualFold(name[len(name)-3:], "_id") {
+		return name[:len(name)-3]
+	}
+	if len(name) > 2 && (strings.HasSuffix(name, "Id") || strings.HasSuffix(name, "ID")) {
+		if r := rune(name[len(name)-3]); unicode.IsLower(r) || unicode.IsDigit(r) {
+			return name[:len(name)-2]
+		}
+	}
+	return ""
+}
+
+// forwardStep returns the join from a foreign key's columns to the columns
+// they reference.
+func forwardStep(fk *data.ForeignKey) *data.RelationStep {
+	return &data.RelationStep{
+		TableDBName:      fk.Table.DBName,
+		ColumnDBNames:    fk.FKColumns.ColumnDBNames(),
+		RefTableDBName:   fk.RefTable.DBName,
+		RefColumnDBNames: fk.FKColumns.RefColumnDBNames(),
+		Table:            fk.Table,
+		Columns:          fkColumns(fk),
+		RefTable:         fk.RefTable,
+		RefColumns:       fkRefColumns(fk),
+		ForeignKey:       fk,
+	}
+}
+
+// reverseStep returns the join from the columns a foreign key references to
+// the foreign key's columns.
+func reverseStep(fk *data.ForeignKey) *data.RelationStep {
+	return &data.RelationStep{
+		TableDBName:      fk.RefTable.DBName,
+		ColumnDBNames:    fk.FKColumns.RefColumnDBNames(),
+		RefTableDBName:   fk.Table.DBName,
+		RefColumnDBNames: fk.FKColumns.ColumnDBNames(),
+		Table:            fk.RefTable,
+		Columns:          fkRefColumns(fk),
+		RefTable:         fk.Table,
+		RefColumns:       fkColumns(fk),
+		ForeignKey:       fk,
+	}
+}
+
+func fkColumns(fk *data.ForeignKey) data.Columns {
+	cols := make(data.Columns, len(fk.FKColumns))
+	for x, c := range fk.FKColumns {
+		cols[x] = c.Column
+	}
+	return cols
+}
+
+func fkRefColumns(fk *data.ForeignKey) data.Columns {
+	cols := make(data.Columns, len(fk.FKColumns))
+	for x, c := range fk.FKColumns {
+		cols[x] = c.RefColumn
+	}
+	return cols
+}
+
+// isUnique reports whether the columns are the table's primary key or the
+// columns of one of its unique indexes, in any order.
+func isUnique(t *data.Table, cols data.Columns) bool {
+	if sameColumns(t.PrimaryKeys, cols) {
+		return true
+	}
+	for _, idx := range t.Indexes {
+		if idx.IsUnique && sameColumns(idx.Columns, cols) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameColumns(a, b data.Columns) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	names := make(map[string]bool, len(a))
+	for _, c := range a {
+		names[c.DBName] = true
+	}
+	for _, c := range b {
+		if !names[c.DBName] {
+			return false
+		}
+	}
+	return true
+}
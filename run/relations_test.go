@@ -0,0 +1,204 @@
+package run
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+// fkColumn returns a column that references the given table's id column.
+func fkColumn(name, table, ref string) *database.Column {
+	return &database.Column{
+		Name:         name,
+		Type:         "integer",
+		IsForeignKey: true,
+		ForeignKey: &database.ForeignKey{
+			SchemaName:        "public",
+			TableName:         table,
+			ColumnName:        name,
+			Name:              table + "_" + name + "_fkey",
+			ForeignTableName:  ref,
+			ForeignColumnName: "id",
+		},
+	}
+}
+
+func idColumn() *database.Column {
+	return &database.Column{Name: "id", Type: "integer", IsPrimaryKey: true, HasDefault: true}
+}
+
+func TestMakeDataRelations(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{pascal .}}`)),
+	}
+	postID, tagID := fkColumn("post_id", "post_tags", "posts"), fkColumn("tag_id", "post_tags", "tags")
+	postID.IsPrimaryKey, tagID.IsPrimaryKey = true, true
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name:    "users",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name: "posts",
+				Columns: []*database.Column{
+					idColumn(),
+					fkColumn("author_id", "posts", "users"),
+					fkColumn("editorId", "posts", "users"),
+					{Name: "title", Type: "text"},
+				},
+			}, {
+				Name: "profiles",
+				Columns: []*database.Column{
+					idColumn(),
+					fkColumn("user_id", "profiles", "users"),
+				},
+				Indexes: []*database.Index{{
+					Name:     "profiles_user_id_key",
+					IsUnique: true,
+					Columns:  []*database.Column{{Name: "user_id"}},
+				}},
+			}, {
+				Name:    "tags",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name: "post_tags",
+				Columns: []*database.Column{
+					postID,
+					tagID,
+					{Name: "created_at", Type: "timestamp", HasDefault: true},
+				},
+			}, {
+				Name: "follows",
+				Columns: []*database.Column{
+					fkColumn("follower_id", "follows", "users"),
+					fkColumn("followee_id", "follows", "users"),
+				},
+				Indexes: []*database.Index{{
+					Name:     "follows_follower_id_followee_id_key",
+					IsUnique: true,
+					Columns:  []*database.Column{{Name: "follower_id"}, {Name: "followee_id"}},
+				}},
+			}, {
+				Name:    "customers",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name:    "addresses",
+				Columns: []*database.Column{idColumn()},
+			}, {
+				Name: "orders",
+				Columns: []*database.Column{
+					idColumn(),
+					fkColumn("customer_id", "orders", "customers"),
+					fkColumn("address_id", "orders", "addresses"),
+					{Name: "created_at", Type: "timestamp", HasDefault: true},
+				},
+			}},
+		}},
+	}
+
+	db, err := makeData(environ.Values{Log: log.New(&bytes.Buffer{}, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	tables := db.SchemasByName["public"].TablesByName
+
+	describe := func(r *data.Relation) string {
+		s := fmt.Sprintf("%s %s %s", r.Name, r.Kind, r.RefTableDBName)
+		if r.Through != nil {
+			s += " through " + r.ThroughDBName
+		}
+		return s
+	}
+	tests := map[string][]string{
+		"users": {
+			"AuthorPosts has-many posts",
+			"EditorPosts has-many posts",
+			"Profiles has-one profiles",
+			"FollowerFollows has-many follows",
+			"FolloweeFollows has-many follows",
+			"FollowsFollowee many-to-many users through follows",
+			"FollowsFollower many-to-many users through follows",
+		},
+		"posts": {
+			"Author belongs-to users",
+			"Editor belongs-to users",
+			"PostTags has-many post_tags",
+			"Tags many-to-many tags through post_tags",
+		},
+		"post_tags": {
+			"Post belongs-to posts",
+			"Tag belongs-to tags",
+		},
+	}
+	for table, expected := range tests {
+		rels := tables[table].Relations
+		if len(rels) != len(expected) {
+			var got []string
+			for _, r := range rels {
+				got = append(got, describe(r))
+			}
+			t.Errorf("%s: expected relations %q, got %q", table, expected, got)
+			continue
+		}
+		for x, r := range rels {
+			if describe(r) != expected[x] {
+				t.Errorf("%s: expected relation %d to be %q, got %q", table, x, expected[x], describe(r))
+			}
+		}
+	}
+
+	if !tables["post_tags"].IsJoinTable || !tables["follows"].IsJoinTable {
+		t.Error("expected post_tags and follows to be join tables")
+	}
+	if tables["posts"].IsJoinTable {
+		t.Error("posts has a column without a default, so it isn't a join table")
+	}
+	if tables["orders"].IsJoinTable {
+		t.Error("orders may link a customer and an address more than once, so it isn't a join table")
+	}
+	for _, r := range tables["customers"].Relations {
+		if r.Kind == data.RelationManyToMany {
+			t.Errorf("expected no many-to-many relations through orders, got %s", describe(r))
+		}
+	}
+
+	tags := tables["posts"].Relations[3]
+	if len(tags.Path) != 2 {
+		t.Fatalf("expected a many-to-many relation to have 2 steps, got %d", len(tags.Path))
+	}
+	first, second := tags.Path[0], tags.Path[1]
+	if first.Table != tables["posts"] || first.RefTable != tables["post_tags"] || first.ColumnDBNames[0] != "id" || first.RefColumnDBNames[0] != "post_id" {
+		t.Errorf("unexpected first step %s%v -> %s%v", first.TableDBName, first.ColumnDBNames, first.RefTableDBName, first.RefColumnDBNames)
+	}
+	if second.Table != tables["post_tags"] || second.RefTable != tables["tags"] || second.Columns[0] != tables["post_tags"].ColumnsByName["tag_id"] || second.RefColumns[0] != tables["tags"].ColumnsByName["id"] {
+		t.Errorf("unexpected second step %s%v -> %s%v", second.TableDBName, second.ColumnDBNames, second.RefTableDBName, second.RefColumnDBNames)
+	}
+}
+
+func TestTrimID(t *testing.T) {
+	tests := map[string]string{
+		"author_id": "author",
+		"AUTHOR_ID": "AUTHOR",
+		"authorId":  "author",
+		"authorID":  "author",
+		"uuid":      "",
+		"UUID":      "",
+		"paid":      "",
+		"_id":       "",
+		"id":        "",
+	}
+	for name, expected := range tests {
+		if got := trimID(name); got != expected {
+			t.Errorf("trimID(%q): expected %q, got %q", name, expected, got)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package run
+
+import (
+	"io/ioutil"
+	"log"
+	"sort"
+
+	"github.com/pkg/errors"
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+// typeMapNames returns the names of the named type maps, sorted.
+func typeMapNames(cfg *Config) []string {
+	var names []string
+	for name := range cfg.TypeMaps {
+		names = append(names, name)
+	}
+	for name := range cfg.NullableTypeMaps {
+		if _, ok := cfg.TypeMaps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// forTypeMap returns a copy of the config whose TypeMap and NullableTypeMap
+// are the named type maps, so that types are mapped with them the same way
+// they are with the default maps, including the TypeRules, Overrides and
+// ArrayType.  An empty name returns the config itself.
+func (c *Config) forTypeMap(name string) *Config {
+	if name == "" {
+		return c
+	}
+	mc := *c
+	mc.TypeMap = c.TypeMaps[name]
+	mc.NullableTypeMap = c.NullableTypeMaps[name]
+	mc.typeMap = name
+	return &mc
+}
+
+// setColumnTypes sets the column's Types from the named type maps.  Each map is
+// resolved the same way the column's Type is.
+func setColumnTypes(probs *problems, cfg *Config, col *data.Column, ov Override) error {
+	names := typeMapNames(cfg)
+	if len(names) == 0 {
+		return nil
+	}
+	col.Types = make(map[string]string, len(names))
+	for _, name := range names {
+		typ, _, _, ok, err := columnType(cfg.forTypeMap(name), col, ov)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			probs.add("Unmapped type %v of column %v.%v.%v in type map %v", col.DBType, col.Table.Schema.DBName, col.Table.DBName, col.DBName, name)
+			continue
+		}
+		col.Types[name] = typ
+	}
+	return nil
+}
+
+// typeMapData returns the data to render each output target with, by the name
+// of the target's type map.  The data for the default maps is db, and the data
+// for each named map the targets use is made from the same info, so that the
+// Type of its columns and the Imports of its tables and schemas come from
+// that map.  Problems were already reported when db was made, so they aren't
+// reported again.
+func typeMapData(cfg *Config, info *database.Info, db *data.DBData) (map[string]*data.DBData, error) {
+	dbs := map[string]*data.DBData{"": db}
+	for _, targets := range [][]OutputTarget{cfg.SchemaPaths, cfg.EnumPaths, cfg.TablePaths} {
+		for _, target := range targets {
+			if _, ok := dbs[target.TypeMap]; ok {
+				continue
+			}
+			mc := cfg.forTypeMap(target.TypeMap)
+			mc.Strict = false
+			mdb, err := makeData(environ.Values{Log: log.New(ioutil.Discard, "", 0)}, info, mc)
+			if err != nil {
+				return nil, errors.WithMessage(err, "type map "+target.TypeMap)
+			}
+			dbs[target.TypeMap] = mdb
+		}
+	}
+	return dbs, nil
+}
@@ -0,0 +1,172 @@
+package run
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+func TestMakeDataTypeMaps(t *testing.T) {
+	t.Parallel()
+
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap: map[string]string{"integer": "int", "text": "string", "uuid": "uuid.UUID"},
+			TypeMaps: map[string]map[string]string{
+				"ts":    {"integer": "number", "text": "string", "text[]": "string[]"},
+				"proto": {"integer": "int32", "text": "string"},
+			},
+			NullableTypeMaps: map[string]map[string]string{
+				"ts": {"text": "string | null"},
+			},
+		},
+		ArrayType: template.Must(template.New("").Parse(`{{if eq .TypeMap "proto"}}repeated {{.Type}}{{else}}[]{{.Type}}{{end}}`)),
+		TypeRules: []TypeRule{{
+			Name:   "ids",
+			DBType: regexp.MustCompile(`^uuid$`),
+			Type:   "ids.ID",
+			Types:  map[string]string{"ts": "ID"},
+		}},
+		Overrides: map[OverrideKey]Override{
+			{Schema: "public", Table: "posts", Column: "secret"}: {Type: "Secret", Types: map[string]string{"proto": "bytes"}},
+		},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "posts",
+				Columns: []*database.Column{
+					{Name: "id", Type: "integer"},
+					{Name: "tags", Type: "text", IsArray: true},
+					{Name: "title", Type: "text", Nullable: true},
+					{Name: "author_id", Type: "uuid"},
+					{Name: "secret", Type: "integer"},
+				},
+			}},
+		}},
+	}
+
+	var logs bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	cols := db.SchemasByName["public"].TablesByName["posts"].ColumnsByName
+	tests := []struct {
+		column string
+		types  map[string]string
+	}{
+		{"id", map[string]string{"ts": "number", "proto": "int32"}},
+		{"tags", map[string]string{"ts": "string[]", "proto": "repeated string"}},
+		{"title", map[string]string{"ts": "string | null"}},
+		// the rule has no type for proto, so it falls back to Type.
+		{"author_id", map[string]string{"ts": "ID", "proto": "ids.ID"}},
+		{"secret", map[string]string{"ts": "Secret", "proto": "bytes"}},
+	}
+	for _, test := range tests {
+		got := cols[test.column].Types
+		if len(got) != len(test.types) {
+			t.Errorf("%s: expected types %v, got %v", test.column, test.types, got)
+			continue
+		}
+		for name, typ := range test.types {
+			if got[name] != typ {
+				t.Errorf("%s: expected type %q from map %s, got %q", test.column, typ, name, got[name])
+			}
+		}
+	}
+	if cols["id"].Type != "int" {
+		t.Errorf("expected the default type to still come from the TypeMap, got %q", cols["id"].Type)
+	}
+	if cols["tags"].Type != "[]string" {
+		t.Errorf("expected the default array type from the ArrayType, got %q", cols["tags"].Type)
+	}
+	if !bytes.Contains(logs.Bytes(), []byte("Unmapped type text of column public.posts.title in type map proto")) {
+		t.Errorf("expected a type missing from a named map to be logged, got %q", logs.String())
+	}
+}
+
+func TestGenerateTargetTypeMaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gnorm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := template.Must(template.New("").Parse(`{{range .Table.Imports}}{{.Path}} {{end}}{{range .Table.Columns}}{{.DBName}} {{.Type}};{{end}}`))
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			OutputDir: dir,
+			TypeMap:   map[string]string{"integer": "int", "uuid": "uuid.UUID"},
+			TypeMaps:  map[string]map[string]string{"ts": {"integer": "number", "uuid": "string"}},
+			TypeImports: map[string]data.Import{
+				"uuid": {Path: "github.com/google/uuid"},
+			},
+		},
+		TypeRules: []TypeRule{{
+			Name:   "counts",
+			Column: regexp.MustCompile(`_count$`),
+			Type:   "int64",
+			Types:  map[string]string{"ts": "bigint"},
+		}},
+		TablePaths: []OutputTarget{{
+			Filename: template.Must(template.New("").Parse(`{{.Table}}.go`)),
+			Contents: contents,
+		}, {
+			Filename: template.Must(template.New("").Parse(`{{.Table}}.ts`)),
+			Contents: contents,
+			TypeMap:  "ts",
+		}},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{Name: "posts", Columns: []*database.Column{
+				{Name: "id", Type: "uuid"},
+				{Name: "view_count", Type: "integer"},
+			}}},
+		}},
+	}
+	env := environ.Values{Log: log.New(ioutil.Discard, "", 0)}
+	db, err := makeData(env, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	dbs, err := typeMapData(c, info, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := generateTables(env, c, dbs); err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		"posts.go": "github.com/google/uuid id uuid.UUID;view_count int64;",
+		"posts.ts": "id string;view_count bigint;",
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, b)
+		}
+	}
+	if len(dbs) != 2 || dbs[""] != db {
+		t.Errorf("expected the data for the default and ts maps, got %d", len(dbs))
+	}
+	if typ := db.SchemasByName["public"].TablesByName["posts"].ColumnsByName["id"].Type; typ != "uuid.UUID" {
+		t.Errorf("expected the default data to keep its types, got %q", typ)
+	}
+}
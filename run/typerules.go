@@ -0,0 +1,82 @@
+package run
+
+import (
+	"regexp"
+
+	"gnorm.org/gnorm/run/data"
+)
+
+// matchTypeRule returns the first of the config's type rules that matches the
+// column and has a type in the config's type map.
+func matchTypeRule(cfg *Config, col *data.Column) (TypeRule, bool) {
+	if len(cfg.TypeRules) == 0 {
+		return TypeRule{}, false
+	}
+	keys := columnTypeKeys(col)
+	for _, r := range cfg.TypeRules {
+		if r.typeIn(cfg.typeMap) != "" && r.matches(col, keys) {
+			return r, true
+		}
+	}
+	return TypeRule{}, false
+}
+
+// typeIn returns the rule's type in the named type map, or in the default
+// maps if the name is empty.
+func (r TypeRule) typeIn(typeMap string) string {
+	if typ, ok := r.Types[typeMap]; ok && typeMap != "" {
+		return typ
+	}
+	return r.Type
+}
+
+// matches reports whether the rule matches the column, whose type may be
+// mapped with the given keys.
+func (r TypeRule) matches(col *data.Column, keys []string) bool {
+	if r.DBType != nil {
+		found := false
+		for _, key := range keys {
+			if r.DBType.MatchString(key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	var schema string
+	if col.Table.Schema != nil {
+		schema = col.Table.Schema.DBName
+	}
+	return matchPattern(r.Schema, schema) &&
+		matchPattern(r.Table, col.Table.DBName) &&
+		matchPattern(r.Column, col.DBName) &&
+		matchInt(r.Length, col.Length) &&
+		matchInt(r.Precision, col.Precision) &&
+		matchInt(r.Scale, col.Scale) &&
+		matchArray(r.Array, col.IsArray) &&
+		matchBool(r.Nullable, col.Nullable)
+}
+
+func matchPattern(re *regexp.Regexp, s string) bool {
+	return re == nil || re.MatchString(s)
+}
+
+func matchInt(want *int, got int) bool {
+	return want == nil || *want == got
+}
+
+// matchArray is like matchBool, except that arrays only match if want is
+// true, since the rule's Type is the type of the whole column, and would
+// otherwise map arrays to their element's type.
+func matchArray(want *bool, got bool) bool {
+	if want == nil {
+		return !got
+	}
+	return *want == got
+}
+
+func matchBool(want *bool, got bool) bool {
+	return want == nil || *want == got
+}
@@ -0,0 +1,111 @@
+package run
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"strings"
+	"testing"
+	"text/template"
+
+	"gnorm.org/gnorm/database"
+	"gnorm.org/gnorm/environ"
+	"gnorm.org/gnorm/run/data"
+)
+
+func TestMakeDataTypeRules(t *testing.T) {
+	t.Parallel()
+
+	yes, no, twelve := true, false, 12
+	c := &Config{
+		NameConversion: template.Must(template.New("").Funcs(environ.FuncMap).Parse(`{{.}}`)),
+		ConfigData: data.ConfigData{
+			TypeMap:         map[string]string{"uuid": "uuid.UUID", "uuid[]": "[]uuid.UUID", "numeric": "float64", "text": "string"},
+			NullableTypeMap: map[string]string{"text": "*string"},
+		},
+		TypeRules: []TypeRule{{
+			Name:   "ids",
+			DBType: regexp.MustCompile(`^uuid$`),
+			Column: regexp.MustCompile(`^.*_id$`),
+			Type:   "ids.UUID",
+		}, {
+			Name:      "money",
+			DBType:    regexp.MustCompile(`^numeric$`),
+			Precision: &twelve,
+			Nullable:  &no,
+			Type:      "money.Amount",
+		}, {
+			Name:   "tags",
+			DBType: regexp.MustCompile(`^text$`),
+			Table:  regexp.MustCompile(`^posts$`),
+			Array:  &yes,
+			Type:   "pq.StringArray",
+		}, {
+			Name:   "never",
+			DBType: regexp.MustCompile(`^uuid$`),
+			Column: regexp.MustCompile(`^.*_id$`),
+			Type:   "unused",
+		}},
+	}
+	info := &database.Info{
+		Schemas: []*database.Schema{{
+			Name: "public",
+			Tables: []*database.Table{{
+				Name: "posts",
+				Columns: []*database.Column{
+					{Name: "id", Type: "uuid"},
+					{Name: "author_id", Type: "uuid"},
+					{Name: "editor_id", Type: "uuid", IsArray: true},
+					{Name: "price", Type: "numeric", Precision: 12, Scale: 2},
+					{Name: "discount", Type: "numeric", Precision: 12, Scale: 2, Nullable: true},
+					{Name: "tags", Type: "text", IsArray: true},
+					{Name: "title", Type: "text", Nullable: true},
+					{Name: "body", Type: "json"},
+				},
+			}},
+		}},
+	}
+
+	var logs bytes.Buffer
+	db, err := makeData(environ.Values{Log: log.New(&logs, "", 0)}, info, c)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	tests := []struct {
+		column string
+		typ    string
+		source string
+	}{
+		{"id", "uuid.UUID", `TypeMap["uuid"]`},
+		{"author_id", "ids.UUID", "ids"},
+		// the ids rule doesn't set Array, so it doesn't match arrays.
+		{"editor_id", "[]uuid.UUID", `TypeMap["uuid[]"]`},
+		{"price", "money.Amount", "money"},
+		{"discount", "", ""},
+		{"tags", "pq.StringArray", "tags"},
+		{"title", "*string", `NullableTypeMap["text"]`},
+		{"body", "", ""},
+	}
+	cols := db.SchemasByName["public"].TablesByName["posts"].ColumnsByName
+	for _, test := range tests {
+		col := cols[test.column]
+		if col.Type != test.typ || col.TypeSource != test.source {
+			t.Errorf("%s: expected type %q from %q, got %q from %q", test.column, test.typ, test.source, col.Type, col.TypeSource)
+		}
+	}
+
+	var out bytes.Buffer
+	displayTypes(environ.Values{Stdout: &out}, c, db)
+	expected := `
+# Columns mapped by TypeRules:
+# public.posts.author_id uuid = "ids.UUID" (ids)
+# public.posts.price numeric = "money.Amount" (money)
+# public.posts.tags text = "pq.StringArray" (tags)
+`
+	if !strings.HasSuffix(out.String(), expected) {
+		t.Errorf("expected types preview to end with %s got %s", expected, out.String())
+	}
+	if strings.Contains(out.String(), `"uuid" = "ids.UUID"`) {
+		t.Errorf("expected columns mapped by rules to be left out of the type maps, got %s", out.String())
+	}
+}